@@ -1,17 +1,30 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/broker"
 	"github.com/ibraheemacara/tezos-delegation-service/config"
 	"github.com/ibraheemacara/tezos-delegation-service/db"
 	"github.com/ibraheemacara/tezos-delegation-service/middlewares"
 	"github.com/penglongli/gin-metrics/ginmetrics"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 )
 
-func StartServer(cfg config.Config, db db.DBInterface) {
+// shutdownTimeout is how long in-flight requests are given to complete once the servers are stopping
+const shutdownTimeout = 10 * time.Second
+
+// StartServer serves the api, the metrics and the gRPC api when it has a port until ctx is cancelled, then drains
+// the servers. The delegations streams are ended by closing the broker.
+func StartServer(ctx context.Context, cfg config.Config, db db.DBInterface, broker *broker.Broker) error {
 	engine := gin.New()
 
 	//metric
@@ -20,17 +33,118 @@ func StartServer(cfg config.Config, db db.DBInterface) {
 	m.UseWithoutExposingEndpoint(engine)
 	m.SetMetricPath("/metrics")
 	m.Expose(metricRouter)
-	go func() {
-		log.Info(fmt.Sprintf("Metrics server started at url http://localhost:%v/metrics", cfg.Server.MetricsPort))
 
-		_ = metricRouter.Run(fmt.Sprintf(":%v", cfg.Server.MetricsPort))
-		log.Fatal("Metrics server stopped")
+	spec, err := LoadSpec()
+	if err != nil {
+		return fmt.Errorf("invalid OpenAPI specification: %w", err)
+	}
+	if err := registerRoutes(engine, cfg, NewController(db, broker), spec); err != nil {
+		return err
+	}
+
+	servers := []*http.Server{
+		{Addr: fmt.Sprintf(":%v", cfg.Server.MetricsPort), Handler: metricRouter},
+		{Addr: fmt.Sprintf(":%v", cfg.Server.Port), Handler: engine},
+	}
+	// the streams never end by themselves, the shutdown would otherwise wait for them until its timeout
+	servers[1].RegisterOnShutdown(broker.Close)
+	log.Info(fmt.Sprintf("Metrics server started at url http://localhost:%v/metrics", cfg.Server.MetricsPort))
+	log.Infof("API server started on port %v", cfg.Server.Port)
+
+	errs := make(chan error, len(servers)+1)
+	for _, server := range servers {
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("server on %s stopped: %w", server.Addr, err)
+			}
+		}()
+	}
+
+	var grpcServer *grpc.Server
+	if cfg.Server.GrpcPort > 0 {
+		grpcServer = NewGrpcServer(db, broker)
+		log.Infof("gRPC server started on port %v", cfg.Server.GrpcPort)
+		go func() {
+			addr := fmt.Sprintf(":%v", cfg.Server.GrpcPort)
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				errs <- fmt.Errorf("gRPC server on %s stopped: %w", addr, err)
+				return
+			}
+			if err := grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				errs <- fmt.Errorf("gRPC server on %s stopped: %w", addr, err)
+			}
+		}()
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-errs:
+	}
+
+	log.Info("Shutting down the api, metrics and gRPC servers")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			serveErr = errors.Join(serveErr, err)
+		}
+	}
+	// the broker is closed by now, which ends the watch streams
+	if grpcServer != nil {
+		stopGrpcServer(shutdownCtx, grpcServer)
+	}
+	return serveErr
+}
+
+// stopGrpcServer waits for the in-flight calls to complete, the calls still running when ctx is done are cancelled
+func stopGrpcServer(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		server.GracefulStop()
 	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+		<-stopped
+	}
+}
 
-	ctrl := NewController(db)
+// registerRoutes adds the routes of the api to engine, each of them is described in spec and its requests are validated against it
+func registerRoutes(engine *gin.Engine, cfg config.Config, ctrl *Controller, spec *openapi3.T) error {
+	validate := middlewares.OpenAPIValidationHandler(spec)
+	getSpec, err := serveSpec(spec)
+	if err != nil {
+		return err
+	}
 
-	engine.GET("/delegations", middlewares.PromReqMetrics(), middlewares.ValidationHandler(), ctrl.GetDelegations, middlewares.LoggerHandler())
-	engine.GET("/delegations/:year", middlewares.PromReqMetrics(), middlewares.ValidationHandler(), ctrl.GetDelegations, middlewares.LoggerHandler())
+	engine.GET("/openapi.json", middlewares.PromReqMetrics(), validate, getSpec, middlewares.LoggerHandler())
+	engine.GET("/delegations", middlewares.PromReqMetrics(), validate, middlewares.ValidationHandler(), ctrl.GetDelegations, middlewares.LoggerHandler())
+	engine.GET("/delegations/export", middlewares.PromReqMetrics(), validate, middlewares.ValidationHandler(), ctrl.ExportDelegations, middlewares.LoggerHandler())
+	engine.GET("/delegations/stream", middlewares.PromReqMetrics(), validate, middlewares.StreamValidationHandler(), ctrl.StreamDelegations, middlewares.LoggerHandler())
+	engine.GET("/delegations/:year", middlewares.PromReqMetrics(), validate, middlewares.ValidationHandler(), ctrl.GetDelegations, middlewares.LoggerHandler())
+	engine.GET("/delegators/:address", middlewares.PromReqMetrics(), validate, middlewares.ValidationHandler(), ctrl.GetDelegator, middlewares.LoggerHandler())
+	engine.GET("/delegators/:address/delegations", middlewares.PromReqMetrics(), validate, middlewares.ValidationHandler(), ctrl.GetDelegationsByDelegator, middlewares.LoggerHandler())
+	engine.GET("/bakers/:address/delegations", middlewares.PromReqMetrics(), validate, middlewares.ValidationHandler(), ctrl.GetDelegationsByBaker, middlewares.LoggerHandler())
+	engine.GET("/bakers/:address/delegators", middlewares.PromReqMetrics(), validate, middlewares.ValidationHandler(), ctrl.GetBakerDelegators, middlewares.LoggerHandler())
+	engine.GET("/stats/delegations", middlewares.PromReqMetrics(), validate, middlewares.StatsValidationHandler(), ctrl.GetDelegationStats, middlewares.LoggerHandler())
+	engine.GET("/leaderboards/delegators", middlewares.PromReqMetrics(), validate, middlewares.LeaderboardValidationHandler(), ctrl.GetDelegatorsLeaderboard, middlewares.LoggerHandler())
+	engine.GET("/leaderboards/delegators/:year", middlewares.PromReqMetrics(), validate, middlewares.LeaderboardValidationHandler(), ctrl.GetDelegatorsLeaderboard, middlewares.LoggerHandler())
+	engine.GET("/leaderboards/bakers", middlewares.PromReqMetrics(), validate, middlewares.LeaderboardValidationHandler(), ctrl.GetBakersLeaderboard, middlewares.LoggerHandler())
+	engine.GET("/leaderboards/bakers/:year", middlewares.PromReqMetrics(), validate, middlewares.LeaderboardValidationHandler(), ctrl.GetBakersLeaderboard, middlewares.LoggerHandler())
 
-	engine.Run(fmt.Sprintf(":%v", cfg.Server.Port))
+	// the admin endpoints are only served when a token protects them
+	if cfg.Admin.Token != "" {
+		admin := engine.Group("/admin", middlewares.PromReqMetrics(), middlewares.AdminAuthHandler(cfg.Admin.Token), validate, middlewares.WebhookValidationHandler())
+		admin.GET("/webhooks", ctrl.GetWebhooks, middlewares.LoggerHandler())
+		admin.POST("/webhooks", ctrl.CreateWebhook, middlewares.LoggerHandler())
+		admin.GET("/webhooks/:id", ctrl.GetWebhook, middlewares.LoggerHandler())
+		admin.PUT("/webhooks/:id", ctrl.UpdateWebhook, middlewares.LoggerHandler())
+		admin.DELETE("/webhooks/:id", ctrl.DeleteWebhook, middlewares.LoggerHandler())
+		admin.GET("/webhooks/:id/dead-letters", ctrl.GetWebhookDeadLetters, middlewares.LoggerHandler())
+	}
+	return nil
 }
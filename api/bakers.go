@@ -0,0 +1,52 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	"github.com/ibraheemacara/tezos-delegation-service/utils"
+)
+
+// GetBakerDelegators returns the delegators whose newest delegation is to the baker along with their totals
+func (ctr *Controller) GetBakerDelegators(ctx *gin.Context) {
+	cursor, limit := pagination(ctx)
+	baker := ctx.Param("address")
+
+	// one extra row is fetched to know if there is a next page
+	delegators, err := ctr.db.GetBakerDelegators(ctx.Request.Context(), baker, cursor, limit+1)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	totals, err := ctr.db.GetBakerTotals(ctx.Request.Context(), baker)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(200, toBakerDelegatorsResponse(baker, delegators, totals, limit))
+}
+
+// toBakerDelegatorsResponse keeps at most limit delegators and sets the next cursor when more are available
+func toBakerDelegatorsResponse(baker string, delegators []db.Delegations, totals db.BakerTotals, limit int) types.BakerDelegatorsResponse {
+	response := types.BakerDelegatorsResponse{
+		Baker:           baker,
+		DelegatorsCount: totals.Delegators,
+		TotalAmount:     totals.TotalAmount,
+		Delegators:      []types.BakerDelegator{},
+	}
+	if len(delegators) > limit {
+		delegators = delegators[:limit]
+		last := delegators[len(delegators)-1]
+		response.NextCursor = utils.EncodeCursor(last.Block, last.ID)
+	}
+	for _, delegation := range delegators {
+		response.Delegators = append(response.Delegators, types.BakerDelegator{
+			Delegator:       delegation.Delegator,
+			Amount:          delegation.Amount,
+			DelegatingSince: delegation.Timestamp,
+			SinceBlock:      delegation.Block,
+		})
+	}
+	return response
+}
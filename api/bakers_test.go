@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/middlewares"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	"github.com/ibraheemacara/tezos-delegation-service/utils"
+)
+
+func newBakerRouter(database db.DBInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	controller := NewController(database, nil)
+	r := gin.New()
+	r.GET("/bakers/:address/delegators", middlewares.ValidationHandler(), controller.GetBakerDelegators)
+	return r
+}
+
+func TestGetBakerDelegators(t *testing.T) {
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mockDB := &MockDB{
+		BakerDelegators: []db.Delegations{
+			{ID: 3, Delegator: testDelegator, Block: 30, Timestamp: since, Amount: 300, NewDelegate: testBaker},
+			{ID: 2, Delegator: "tz1other", Block: 20, Timestamp: since.Add(-time.Hour), Amount: 200, NewDelegate: testBaker},
+		},
+		BakerTotals: db.BakerTotals{Delegators: 2, TotalAmount: 500},
+	}
+	r := newBakerRouter(mockDB)
+
+	req := httptest.NewRequest("GET", "/bakers/"+testBaker+"/delegators?limit=1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response types.BakerDelegatorsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if mockDB.Baker != testBaker || mockDB.Limit != 2 {
+		t.Errorf("expected delegators of %s with limit 2, got %s with %d", testBaker, mockDB.Baker, mockDB.Limit)
+	}
+	if response.Baker != testBaker || response.DelegatorsCount != 2 || response.TotalAmount != 500 {
+		t.Errorf("expected totals of 2 delegators and 500, got %+v", response)
+	}
+	if len(response.Delegators) != 1 {
+		t.Fatalf("expected 1 delegator, got %d", len(response.Delegators))
+	}
+	delegator := response.Delegators[0]
+	if delegator.Delegator != testDelegator || delegator.Amount != 300 || delegator.SinceBlock != 30 || !delegator.DelegatingSince.Equal(since) {
+		t.Errorf("unexpected delegator %+v", delegator)
+	}
+	if response.NextCursor != utils.EncodeCursor(30, 3) {
+		t.Errorf("expected the next cursor after block 30, got %q", response.NextCursor)
+	}
+}
+
+func TestGetBakerDelegatorsEmpty(t *testing.T) {
+	r := newBakerRouter(&MockDB{})
+
+	req := httptest.NewRequest("GET", "/bakers/"+testBaker+"/delegators", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var response types.BakerDelegatorsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Delegators == nil || len(response.Delegators) != 0 || response.NextCursor != "" {
+		t.Errorf("expected an empty list of delegators, got %+v", response)
+	}
+}
+
+func TestGetBakerDelegatorsError(t *testing.T) {
+	for _, mockDB := range []*MockDBError{
+		{BakerDelegatorsErr: errors.New("test error")},
+		{BakerTotalsErr: errors.New("test error")},
+	} {
+		r := newBakerRouter(mockDB)
+
+		req := httptest.NewRequest("GET", "/bakers/"+testBaker+"/delegators", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != 500 {
+			t.Errorf("expected status 500, got %d", w.Code)
+		}
+	}
+}
@@ -1,55 +1,95 @@
 package api
 
 import (
-	"strconv"
-
 	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/broker"
 	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/middlewares"
 	"github.com/ibraheemacara/tezos-delegation-service/types"
+	"github.com/ibraheemacara/tezos-delegation-service/utils"
 )
 
 type Controller struct {
-	db db.DBInterface
+	db     db.DBInterface
+	broker *broker.Broker
 }
 
-func NewController(db db.DBInterface) *Controller {
-	return &Controller{db: db}
+func NewController(db db.DBInterface, broker *broker.Broker) *Controller {
+	return &Controller{db: db, broker: broker}
 }
 
 func (ctr *Controller) GetDelegations(ctx *gin.Context) {
-	year, ok := ctx.Get("year")
-	if !ok {
-		delegations, err := ctr.db.GetDelegations()
-		if err != nil {
-			ctx.JSON(500, gin.H{"error": "Internal server error"})
-			return
-		}
-		var data []types.Delegation
-		for _, delegation := range delegations {
-			data = append(data, types.Delegation{
-				Delegator: delegation.Delegator,
-				Timestamp: delegation.Timestamp,
-				Block:     delegation.Block,
-				Amount:    delegation.Amount,
-			})
-		}
-		ctx.JSON(200, types.DelegationsResponse{Delegations: data})
-	} else {
-		yearStr := year.(int)
-		delegations, err := ctr.db.GetDelegationsByYear(strconv.Itoa(yearStr))
-		if err != nil {
-			ctx.JSON(500, gin.H{"error": "Internal server error"})
-			return
-		}
-		var data []types.Delegation
-		for _, delegation := range delegations {
-			data = append(data, types.Delegation{
-				Delegator: delegation.Delegator,
-				Timestamp: delegation.Timestamp,
-				Block:     delegation.Block,
-				Amount:    delegation.Amount,
-			})
-		}
-		ctx.JSON(200, types.DelegationsResponse{Delegations: data})
+	cursor, limit := pagination(ctx)
+
+	var filter db.DelegationsFilter
+	if value, ok := ctx.Get("filter"); ok {
+		filter = value.(db.DelegationsFilter)
+	}
+
+	// one extra row is fetched to know if there is a next page
+	delegations, err := ctr.db.GetDelegations(ctx.Request.Context(), filter, cursor, limit+1)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(200, toDelegationsResponse(delegations, limit))
+}
+
+func (ctr *Controller) GetDelegationsByDelegator(ctx *gin.Context) {
+	cursor, limit := pagination(ctx)
+
+	delegations, err := ctr.db.GetDelegationsByDelegator(ctx.Request.Context(), ctx.Param("address"), cursor, limit+1)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(200, toDelegationsResponse(delegations, limit))
+}
+
+func (ctr *Controller) GetDelegationsByBaker(ctx *gin.Context) {
+	cursor, limit := pagination(ctx)
+
+	delegations, err := ctr.db.GetDelegationsByBaker(ctx.Request.Context(), ctx.Param("address"), cursor, limit+1)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(200, toDelegationsResponse(delegations, limit))
+}
+
+// pagination reads the cursor and limit set by the validation middleware
+func pagination(ctx *gin.Context) (*db.Cursor, int) {
+	limit := middlewares.DefaultLimit
+	if value, ok := ctx.Get("limit"); ok {
+		limit = value.(int)
+	}
+	var cursor *db.Cursor
+	if value, ok := ctx.Get("cursor"); ok {
+		cursor = value.(*db.Cursor)
+	}
+	return cursor, limit
+}
+
+// toDelegationsResponse keeps at most limit delegations and sets the next cursor when more are available
+func toDelegationsResponse(delegations []db.Delegations, limit int) types.DelegationsResponse {
+	var response types.DelegationsResponse
+	if len(delegations) > limit {
+		delegations = delegations[:limit]
+		last := delegations[len(delegations)-1]
+		response.NextCursor = utils.EncodeCursor(last.Block, last.ID)
+	}
+	for _, delegation := range delegations {
+		response.Delegations = append(response.Delegations, types.Delegation{
+			Delegator:    delegation.Delegator,
+			Timestamp:    delegation.Timestamp,
+			Block:        delegation.Block,
+			Amount:       delegation.Amount,
+			NewDelegate:  delegation.NewDelegate,
+			PrevDelegate: delegation.PrevDelegate,
+		})
 	}
+	return response
 }
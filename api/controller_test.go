@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http/httptest"
@@ -9,59 +10,314 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/middlewares"
 	"github.com/ibraheemacara/tezos-delegation-service/types"
+	"github.com/ibraheemacara/tezos-delegation-service/utils"
 )
 
 type MockDB struct {
 	DelegationsToGet []db.Delegations
+	Filter           db.DelegationsFilter
+	Cursor           *db.Cursor
+	Limit            int
+	Delegator        string
+	Baker            string
+	Webhooks         []db.Webhook
+	DeadLetters      []db.WebhookDeadLetter
+	Stats            []db.DelegationStats
+	StatsQuery       db.StatsQuery
+	Leaderboard      []db.LeaderboardEntry
+	LeaderboardQuery db.LeaderboardQuery
+	History          []db.Delegations
+	BakerDelegators  []db.Delegations
+	BakerTotals      db.BakerTotals
+	ExportBatches    [][]db.Delegations
 }
 
-func (m *MockDB) GetDelegations() ([]db.Delegations, error) {
+func (m *MockDB) GetDelegations(ctx context.Context, filter db.DelegationsFilter, cursor *db.Cursor, limit int) ([]db.Delegations, error) {
+	m.Filter = filter
+	m.Cursor = cursor
+	m.Limit = limit
 	return m.DelegationsToGet, nil
 }
 
-func (m *MockDB) GetDelegationsByYear(year string) ([]db.Delegations, error) {
+func (m *MockDB) ExportDelegations(ctx context.Context, filter db.DelegationsFilter, write func([]db.Delegations) error) error {
+	m.Filter = filter
+	for _, batch := range m.ExportBatches {
+		if err := write(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockDB) GetDelegationsByDelegator(ctx context.Context, delegator string, cursor *db.Cursor, limit int) ([]db.Delegations, error) {
+	m.Delegator = delegator
+	m.Cursor = cursor
+	m.Limit = limit
 	return m.DelegationsToGet, nil
 }
 
-func (m *MockDB) InsertDelegations(delegator string, timestamp time.Time, block int32, amount int64) error {
+func (m *MockDB) GetDelegationsByBaker(ctx context.Context, baker string, cursor *db.Cursor, limit int) ([]db.Delegations, error) {
+	m.Baker = baker
+	m.Cursor = cursor
+	m.Limit = limit
+	return m.DelegationsToGet, nil
+}
+
+func (m *MockDB) InsertDelegations(ctx context.Context, delegation db.Delegations) error {
 	return nil
 }
 
-func (m *MockDB) GetLastBlock() (int32, error) {
+func (m *MockDB) GetSyncLevel(ctx context.Context, stream string) (int32, error) {
 	return 0, nil
 }
 
-func (m *MockDB) BulkInsertDelegations(delegations []db.Delegations) error {
+func (m *MockDB) GetLastBlock(ctx context.Context) (int32, error) {
+	return 0, nil
+}
+
+func (m *MockDB) UpdateSyncLevel(ctx context.Context, stream string, level int32) error {
+	return nil
+}
+
+func (m *MockDB) BulkInsertDelegations(ctx context.Context, delegations []db.Delegations, checkpoint *db.SyncState) ([]db.Delegations, error) {
+	return delegations, nil
+}
+
+func (m *MockDB) DeleteDelegationsAboveBlock(ctx context.Context, block int32) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockDB) GetBackfillChunks(ctx context.Context, afterLevel int32) ([]db.BackfillChunk, error) {
+	return nil, nil
+}
+
+func (m *MockDB) CreateBackfillChunks(ctx context.Context, chunks []db.BackfillChunk) error {
+	return nil
+}
+
+func (m *MockDB) CompleteBackfillChunk(ctx context.Context, fromLevel int32) error {
+	return nil
+}
+func (m *MockDB) GetDelegatorHistory(ctx context.Context, delegator string) ([]db.Delegations, error) {
+	m.Delegator = delegator
+	return m.History, nil
+}
+
+func (m *MockDB) GetBakerDelegators(ctx context.Context, baker string, cursor *db.Cursor, limit int) ([]db.Delegations, error) {
+	m.Baker = baker
+	m.Cursor = cursor
+	m.Limit = limit
+	return m.BakerDelegators, nil
+}
+
+func (m *MockDB) GetBakerTotals(ctx context.Context, baker string) (db.BakerTotals, error) {
+	return m.BakerTotals, nil
+}
+
+func (m *MockDB) GetDelegationStats(ctx context.Context, query db.StatsQuery) ([]db.DelegationStats, error) {
+	m.StatsQuery = query
+	return m.Stats, nil
+}
+
+func (m *MockDB) GetLeaderboard(ctx context.Context, query db.LeaderboardQuery) ([]db.LeaderboardEntry, error) {
+	m.LeaderboardQuery = query
+	return m.Leaderboard, nil
+}
+
+func (m *MockDB) RefreshLeaderboards(ctx context.Context) error {
+	return nil
+}
+
+func (m *MockDB) GetWebhooks(ctx context.Context) ([]db.Webhook, error) {
+	return m.Webhooks, nil
+}
+
+func (m *MockDB) GetWebhook(ctx context.Context, id uint) (db.Webhook, error) {
+	for _, webhook := range m.Webhooks {
+		if webhook.ID == id {
+			return webhook, nil
+		}
+	}
+	return db.Webhook{}, db.ErrNotFound
+}
+
+func (m *MockDB) CreateWebhook(ctx context.Context, webhook *db.Webhook) error {
+	webhook.ID = uint(len(m.Webhooks) + 1)
+	m.Webhooks = append(m.Webhooks, *webhook)
+	return nil
+}
+
+func (m *MockDB) UpdateWebhook(ctx context.Context, webhook *db.Webhook) error {
+	for i := range m.Webhooks {
+		if m.Webhooks[i].ID == webhook.ID {
+			m.Webhooks[i] = *webhook
+			return nil
+		}
+	}
+	return db.ErrNotFound
+}
+
+func (m *MockDB) DeleteWebhook(ctx context.Context, id uint) error {
+	for i := range m.Webhooks {
+		if m.Webhooks[i].ID == id {
+			m.Webhooks = append(m.Webhooks[:i], m.Webhooks[i+1:]...)
+			return nil
+		}
+	}
+	return db.ErrNotFound
+}
+
+func (m *MockDB) InsertWebhookDeadLetter(ctx context.Context, deadLetter db.WebhookDeadLetter) error {
+	m.DeadLetters = append(m.DeadLetters, deadLetter)
+	return nil
+}
+
+func (m *MockDB) GetWebhookDeadLetters(ctx context.Context, webhookID uint) ([]db.WebhookDeadLetter, error) {
+	var deadLetters []db.WebhookDeadLetter
+	for _, deadLetter := range m.DeadLetters {
+		if deadLetter.WebhookID == webhookID {
+			deadLetters = append(deadLetters, deadLetter)
+		}
+	}
+	return deadLetters, nil
+}
+
+func (m *MockDB) Close() error {
 	return nil
 }
 
 type MockDBError struct {
 	GetDelegationsError      error
-	GetDelegationsByYearErr  error
+	GetByDelegatorErr        error
+	GetByBakerErr            error
 	InsertDelegationsErr     error
-	GetLastBlockErr          error
+	GetSyncLevelErr          error
+	UpdateSyncLevelErr       error
 	BulkInsertDelegationsErr error
+	WebhooksErr              error
+	StatsErr                 error
+	LeaderboardErr           error
+	HistoryErr               error
+	BakerDelegatorsErr       error
+	BakerTotalsErr           error
+	ExportBatches            [][]db.Delegations
+	ExportErr                error
 }
 
-func (m *MockDBError) GetDelegations() ([]db.Delegations, error) {
+func (m *MockDBError) GetDelegations(ctx context.Context, filter db.DelegationsFilter, cursor *db.Cursor, limit int) ([]db.Delegations, error) {
 	return nil, m.GetDelegationsError
 }
 
-func (m *MockDBError) GetDelegationsByYear(year string) ([]db.Delegations, error) {
-	return nil, m.GetDelegationsByYearErr
+// ExportDelegations writes the batches, if any, before failing
+func (m *MockDBError) ExportDelegations(ctx context.Context, filter db.DelegationsFilter, write func([]db.Delegations) error) error {
+	for _, batch := range m.ExportBatches {
+		if err := write(batch); err != nil {
+			return err
+		}
+	}
+	return m.ExportErr
+}
+
+func (m *MockDBError) GetDelegationsByDelegator(ctx context.Context, delegator string, cursor *db.Cursor, limit int) ([]db.Delegations, error) {
+	return nil, m.GetByDelegatorErr
+}
+
+func (m *MockDBError) GetDelegationsByBaker(ctx context.Context, baker string, cursor *db.Cursor, limit int) ([]db.Delegations, error) {
+	return nil, m.GetByBakerErr
 }
 
-func (m *MockDBError) InsertDelegations(delegator string, timestamp time.Time, block int32, amount int64) error {
+func (m *MockDBError) InsertDelegations(ctx context.Context, delegation db.Delegations) error {
 	return m.InsertDelegationsErr
 }
 
-func (m *MockDBError) GetLastBlock() (int32, error) {
-	return 0, m.GetLastBlockErr
+func (m *MockDBError) GetSyncLevel(ctx context.Context, stream string) (int32, error) {
+	return 0, m.GetSyncLevelErr
 }
 
-func (m *MockDBError) BulkInsertDelegations(delegations []db.Delegations) error {
-	return m.BulkInsertDelegationsErr
+func (m *MockDBError) GetLastBlock(ctx context.Context) (int32, error) {
+	return 0, nil
+}
+
+func (m *MockDBError) UpdateSyncLevel(ctx context.Context, stream string, level int32) error {
+	return m.UpdateSyncLevelErr
+}
+
+func (m *MockDBError) BulkInsertDelegations(ctx context.Context, delegations []db.Delegations, checkpoint *db.SyncState) ([]db.Delegations, error) {
+	return nil, m.BulkInsertDelegationsErr
+}
+
+func (m *MockDBError) DeleteDelegationsAboveBlock(ctx context.Context, block int32) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockDBError) GetBackfillChunks(ctx context.Context, afterLevel int32) ([]db.BackfillChunk, error) {
+	return nil, nil
+}
+
+func (m *MockDBError) CreateBackfillChunks(ctx context.Context, chunks []db.BackfillChunk) error {
+	return nil
+}
+
+func (m *MockDBError) CompleteBackfillChunk(ctx context.Context, fromLevel int32) error {
+	return nil
+}
+
+func (m *MockDBError) GetDelegatorHistory(ctx context.Context, delegator string) ([]db.Delegations, error) {
+	return nil, m.HistoryErr
+}
+
+func (m *MockDBError) GetBakerDelegators(ctx context.Context, baker string, cursor *db.Cursor, limit int) ([]db.Delegations, error) {
+	return nil, m.BakerDelegatorsErr
+}
+
+func (m *MockDBError) GetBakerTotals(ctx context.Context, baker string) (db.BakerTotals, error) {
+	return db.BakerTotals{}, m.BakerTotalsErr
+}
+
+func (m *MockDBError) GetDelegationStats(ctx context.Context, query db.StatsQuery) ([]db.DelegationStats, error) {
+	return nil, m.StatsErr
+}
+
+func (m *MockDBError) GetLeaderboard(ctx context.Context, query db.LeaderboardQuery) ([]db.LeaderboardEntry, error) {
+	return nil, m.LeaderboardErr
+}
+
+func (m *MockDBError) RefreshLeaderboards(ctx context.Context) error {
+	return m.LeaderboardErr
+}
+
+func (m *MockDBError) GetWebhooks(ctx context.Context) ([]db.Webhook, error) {
+	return nil, m.WebhooksErr
+}
+
+func (m *MockDBError) GetWebhook(ctx context.Context, id uint) (db.Webhook, error) {
+	return db.Webhook{}, m.WebhooksErr
+}
+
+func (m *MockDBError) CreateWebhook(ctx context.Context, webhook *db.Webhook) error {
+	return m.WebhooksErr
+}
+
+func (m *MockDBError) UpdateWebhook(ctx context.Context, webhook *db.Webhook) error {
+	return m.WebhooksErr
+}
+
+func (m *MockDBError) DeleteWebhook(ctx context.Context, id uint) error {
+	return m.WebhooksErr
+}
+
+func (m *MockDBError) InsertWebhookDeadLetter(ctx context.Context, deadLetter db.WebhookDeadLetter) error {
+	return m.WebhooksErr
+}
+
+func (m *MockDBError) GetWebhookDeadLetters(ctx context.Context, webhookID uint) ([]db.WebhookDeadLetter, error) {
+	return nil, m.WebhooksErr
+}
+
+func (m *MockDBError) Close() error {
+	return nil
 }
 
 func TestGetDelegations(t *testing.T) {
@@ -94,7 +350,7 @@ func TestGetDelegations(t *testing.T) {
 		},
 	}
 
-	controller := NewController(mockDB)
+	controller := NewController(mockDB, nil)
 
 	r := gin.New()
 	r.GET("/delegations", controller.GetDelegations)
@@ -127,7 +383,7 @@ func TestGetDelegationsError(t *testing.T) {
 	mockDB := &MockDBError{
 		GetDelegationsError: errors.New("test error"),
 	}
-	controller := NewController(mockDB)
+	controller := NewController(mockDB, nil)
 
 	r := gin.New()
 	r.GET("/delegations", controller.GetDelegations)
@@ -140,3 +396,306 @@ func TestGetDelegationsError(t *testing.T) {
 		t.Fatalf("expected status 500, got %d", w.Code)
 	}
 }
+
+func TestGetDelegationsPagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockDB := &MockDB{
+		DelegationsToGet: []db.Delegations{
+			{ID: 3, Delegator: "tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd", Block: 30, Amount: 1},
+			{ID: 2, Delegator: "tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd", Block: 20, Amount: 2},
+			{ID: 1, Delegator: "tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd", Block: 10, Amount: 3},
+		},
+	}
+	controller := NewController(mockDB, nil)
+
+	r := gin.New()
+	r.GET("/delegations", middlewares.ValidationHandler(), controller.GetDelegations)
+
+	req := httptest.NewRequest("GET", "/delegations?limit=2&cursor="+utils.EncodeCursor(40, 4), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if mockDB.Limit != 3 {
+		t.Errorf("expected db limit 3, got %d", mockDB.Limit)
+	}
+	if mockDB.Cursor == nil || mockDB.Cursor.Block != 40 || mockDB.Cursor.ID != 4 {
+		t.Errorf("expected cursor {40 4}, got %v", mockDB.Cursor)
+	}
+
+	var response types.DelegationsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Delegations) != 2 {
+		t.Fatalf("expected 2 delegations, got %d", len(response.Delegations))
+	}
+	if response.NextCursor != utils.EncodeCursor(20, 2) {
+		t.Errorf("expected next cursor %s, got %s", utils.EncodeCursor(20, 2), response.NextCursor)
+	}
+}
+
+func TestGetDelegationsLastPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockDB := &MockDB{
+		DelegationsToGet: []db.Delegations{
+			{ID: 1, Delegator: "tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd", Block: 10, Amount: 3},
+		},
+	}
+	controller := NewController(mockDB, nil)
+
+	r := gin.New()
+	r.GET("/delegations", middlewares.ValidationHandler(), controller.GetDelegations)
+
+	req := httptest.NewRequest("GET", "/delegations", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if mockDB.Limit != middlewares.DefaultLimit+1 {
+		t.Errorf("expected db limit %d, got %d", middlewares.DefaultLimit+1, mockDB.Limit)
+	}
+
+	var response types.DelegationsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.NextCursor != "" {
+		t.Errorf("expected no next cursor, got %s", response.NextCursor)
+	}
+}
+
+func TestGetDelegationsInvalidPagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	controller := NewController(&MockDB{}, nil)
+
+	validate := openAPIValidator(t)
+	r := gin.New()
+	r.GET("/delegations", validate, middlewares.ValidationHandler(), controller.GetDelegations)
+
+	for _, query := range []string{"limit=0", "limit=abc", "limit=1001", "cursor=invalid"} {
+		req := httptest.NewRequest("GET", "/delegations?"+query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != 400 {
+			t.Errorf("expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestGetDelegationsFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockDB := &MockDB{}
+	controller := NewController(mockDB, nil)
+
+	r := gin.New()
+	r.GET("/delegations", middlewares.ValidationHandler(), controller.GetDelegations)
+	r.GET("/delegations/:year", middlewares.ValidationHandler(), controller.GetDelegations)
+
+	req := httptest.NewRequest("GET", "/delegations/2024?from=2024-03-01T00:00:00Z&to=2024-04-01&block_from=100&block_to=100&min_amount=0&max_amount=5000", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	filter := mockDB.Filter
+	if filter.Year != 2024 {
+		t.Errorf("expected year 2024, got %d", filter.Year)
+	}
+	if !filter.From.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) || !filter.To.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected from 2024-03-01 to 2024-04-01, got %v to %v", filter.From, filter.To)
+	}
+	if filter.BlockFrom == nil || *filter.BlockFrom != 100 || filter.BlockTo == nil || *filter.BlockTo != 100 {
+		t.Errorf("expected blocks 100 to 100, got %v to %v", filter.BlockFrom, filter.BlockTo)
+	}
+	if filter.MinAmount == nil || *filter.MinAmount != 0 || filter.MaxAmount == nil || *filter.MaxAmount != 5000 {
+		t.Errorf("expected amounts 0 to 5000, got %v to %v", filter.MinAmount, filter.MaxAmount)
+	}
+
+	req = httptest.NewRequest("GET", "/delegations", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if mockDB.Filter != (db.DelegationsFilter{}) {
+		t.Errorf("expected no filter, got %+v", mockDB.Filter)
+	}
+}
+
+func TestGetDelegationsInvalidFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	controller := NewController(&MockDB{}, nil)
+
+	validate := openAPIValidator(t)
+	r := gin.New()
+	r.GET("/delegations", validate, middlewares.ValidationHandler(), controller.GetDelegations)
+	r.GET("/delegations/:year", validate, middlewares.ValidationHandler(), controller.GetDelegations)
+
+	for _, path := range []string{
+		"/delegations?from=yesterday",
+		"/delegations?to=2024-13-01",
+		"/delegations?from=2024-02-01&to=2024-01-01",
+		"/delegations?from=2024-01-01&to=2024-01-01",
+		"/delegations?block_from=-1",
+		"/delegations?block_to=abc",
+		"/delegations?block_from=200&block_to=100",
+		"/delegations?min_amount=-5",
+		"/delegations?max_amount=1.5",
+		"/delegations?min_amount=10&max_amount=5",
+		"/delegations/2023?from=2024-01-01",
+		"/delegations/2024?to=2024-01-01",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != 400 {
+			t.Errorf("expected status 400 for %s, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestGetDelegationsByDelegator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	delegator := "tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd"
+	mockDB := &MockDB{
+		DelegationsToGet: []db.Delegations{
+			{ID: 1, Delegator: delegator, Block: 10, Amount: 3},
+		},
+	}
+	controller := NewController(mockDB, nil)
+
+	r := gin.New()
+	r.GET("/delegators/:address/delegations", middlewares.ValidationHandler(), controller.GetDelegationsByDelegator)
+
+	req := httptest.NewRequest("GET", "/delegators/"+delegator+"/delegations", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if mockDB.Delegator != delegator {
+		t.Errorf("expected delegator %s, got %s", delegator, mockDB.Delegator)
+	}
+
+	var response types.DelegationsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Delegations) != 1 || response.Delegations[0].Delegator != delegator {
+		t.Errorf("unexpected delegations: %+v", response.Delegations)
+	}
+}
+
+func TestGetDelegationsByDelegatorInvalidAddress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	controller := NewController(&MockDB{}, nil)
+
+	validate := openAPIValidator(t)
+	r := gin.New()
+	r.GET("/delegators/:address/delegations", validate, middlewares.ValidationHandler(), controller.GetDelegationsByDelegator)
+
+	req := httptest.NewRequest("GET", "/delegators/tz1invalid/delegations", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 400 {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestGetDelegationsByDelegatorError(t *testing.T) {
+	mockDB := &MockDBError{
+		GetByDelegatorErr: errors.New("test error"),
+	}
+	controller := NewController(mockDB, nil)
+
+	r := gin.New()
+	r.GET("/delegators/:address/delegations", controller.GetDelegationsByDelegator)
+
+	req := httptest.NewRequest("GET", "/delegators/tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd/delegations", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 500 {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+}
+
+func TestGetDelegationsByBaker(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	baker := "tz3WXYtyDUNL91qfiCJtVUX746QpNv5i5ve5"
+	mockDB := &MockDB{
+		DelegationsToGet: []db.Delegations{
+			{
+				ID:           1,
+				Delegator:    "tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd",
+				Block:        10,
+				Amount:       3,
+				NewDelegate:  baker,
+				PrevDelegate: "tz2Ch1abG7FNiibmV26Uzgdsnfni9XGrk5wD",
+			},
+		},
+	}
+	controller := NewController(mockDB, nil)
+
+	r := gin.New()
+	r.GET("/bakers/:address/delegations", middlewares.ValidationHandler(), controller.GetDelegationsByBaker)
+
+	req := httptest.NewRequest("GET", "/bakers/"+baker+"/delegations", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if mockDB.Baker != baker {
+		t.Errorf("expected baker %s, got %s", baker, mockDB.Baker)
+	}
+
+	var response types.DelegationsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Delegations) != 1 {
+		t.Fatalf("expected 1 delegation, got %d", len(response.Delegations))
+	}
+	if response.Delegations[0].NewDelegate != baker || response.Delegations[0].PrevDelegate != "tz2Ch1abG7FNiibmV26Uzgdsnfni9XGrk5wD" {
+		t.Errorf("unexpected delegates: %+v", response.Delegations[0])
+	}
+}
+
+func TestGetDelegationsByBakerError(t *testing.T) {
+	mockDB := &MockDBError{
+		GetByBakerErr: errors.New("test error"),
+	}
+	controller := NewController(mockDB, nil)
+
+	r := gin.New()
+	r.GET("/bakers/:address/delegations", controller.GetDelegationsByBaker)
+
+	req := httptest.NewRequest("GET", "/bakers/tz3WXYtyDUNL91qfiCJtVUX746QpNv5i5ve5/delegations", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 500 {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+}
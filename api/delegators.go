@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+// GetDelegator returns the current delegate of the delegator and the history of its delegations
+func (ctr *Controller) GetDelegator(ctx *gin.Context) {
+	address := ctx.Param("address")
+	history, err := ctr.db.GetDelegatorHistory(ctx.Request.Context(), address)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	if len(history) == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Delegator not found"})
+		return
+	}
+
+	ctx.JSON(200, toDelegatorTimeline(address, history))
+}
+
+// toDelegatorTimeline resolves the current delegate from the history ordered from the oldest to the newest delegation.
+// Delegating again to the same delegate does not change when the delegator started delegating to it.
+func toDelegatorTimeline(address string, history []db.Delegations) types.DelegatorTimeline {
+	timeline := types.DelegatorTimeline{Delegator: address, History: make([]types.Delegation, len(history))}
+	for i, delegation := range history {
+		timeline.History[i] = types.Delegation{
+			Delegator:    delegation.Delegator,
+			Timestamp:    delegation.Timestamp,
+			Block:        delegation.Block,
+			Amount:       delegation.Amount,
+			NewDelegate:  delegation.NewDelegate,
+			PrevDelegate: delegation.PrevDelegate,
+		}
+	}
+
+	newest := history[len(history)-1]
+	timeline.CurrentDelegate = newest.NewDelegate
+	// a removal of the delegate is an undelegation, the delegator is not delegating anymore
+	if newest.NewDelegate == "" {
+		return timeline
+	}
+
+	start := len(history) - 1
+	for start > 0 && history[start-1].NewDelegate == newest.NewDelegate {
+		start--
+	}
+	since := history[start]
+	timeline.DelegatingSince = &since.Timestamp
+	timeline.SinceBlock = since.Block
+	timeline.Amount = since.Amount
+	return timeline
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/middlewares"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+func newDelegatorRouter(t *testing.T, database db.DBInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	controller := NewController(database, nil)
+	r := gin.New()
+	r.GET("/delegators/:address", openAPIValidator(t), middlewares.ValidationHandler(), controller.GetDelegator)
+	return r
+}
+
+func getDelegatorTimeline(t *testing.T, r *gin.Engine) types.DelegatorTimeline {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/delegators/"+testDelegator, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var timeline types.DelegatorTimeline
+	if err := json.Unmarshal(w.Body.Bytes(), &timeline); err != nil {
+		t.Fatal(err)
+	}
+	return timeline
+}
+
+func TestGetDelegator(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mockDB := &MockDB{History: []db.Delegations{
+		{ID: 1, Delegator: testDelegator, Block: 10, Timestamp: start, Amount: 100, NewDelegate: "tz1first"},
+		{ID: 2, Delegator: testDelegator, Block: 20, Timestamp: start.Add(time.Hour), Amount: 200, NewDelegate: testBaker, PrevDelegate: "tz1first"},
+		{ID: 3, Delegator: testDelegator, Block: 30, Timestamp: start.Add(2 * time.Hour), Amount: 300, NewDelegate: testBaker, PrevDelegate: testBaker},
+	}}
+	timeline := getDelegatorTimeline(t, newDelegatorRouter(t, mockDB))
+
+	if mockDB.Delegator != testDelegator || timeline.Delegator != testDelegator {
+		t.Errorf("expected the timeline of %s, got %s", testDelegator, timeline.Delegator)
+	}
+	if timeline.CurrentDelegate != testBaker {
+		t.Errorf("expected current delegate %s, got %q", testBaker, timeline.CurrentDelegate)
+	}
+	// delegating again to the same baker keeps the start of the delegation
+	if timeline.DelegatingSince == nil || !timeline.DelegatingSince.Equal(start.Add(time.Hour)) || timeline.SinceBlock != 20 || timeline.Amount != 200 {
+		t.Errorf("expected delegating since block 20 with 200, got %v at block %d with %d", timeline.DelegatingSince, timeline.SinceBlock, timeline.Amount)
+	}
+	if len(timeline.History) != 3 || timeline.History[0].Block != 10 || timeline.History[2].Block != 30 {
+		t.Errorf("expected the history from the oldest to the newest delegation, got %+v", timeline.History)
+	}
+}
+
+func TestGetDelegatorUndelegated(t *testing.T) {
+	mockDB := &MockDB{History: []db.Delegations{
+		{ID: 1, Delegator: testDelegator, Block: 10, Amount: 100, NewDelegate: testBaker},
+		{ID: 2, Delegator: testDelegator, Block: 20, Amount: 200, PrevDelegate: testBaker},
+	}}
+	timeline := getDelegatorTimeline(t, newDelegatorRouter(t, mockDB))
+
+	if timeline.CurrentDelegate != "" || timeline.DelegatingSince != nil || timeline.SinceBlock != 0 || timeline.Amount != 0 {
+		t.Errorf("expected no current delegate, got %+v", timeline)
+	}
+	if len(timeline.History) != 2 {
+		t.Errorf("expected 2 delegations, got %d", len(timeline.History))
+	}
+}
+
+func TestGetDelegatorNotFound(t *testing.T) {
+	r := newDelegatorRouter(t, &MockDB{})
+
+	req := httptest.NewRequest("GET", "/delegators/"+testDelegator, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/delegators/tz1invalid", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestGetDelegatorError(t *testing.T) {
+	r := newDelegatorRouter(t, &MockDBError{HistoryErr: errors.New("test error")})
+
+	req := httptest.NewRequest("GET", "/delegators/"+testDelegator, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 500 {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	mimeCSV    = "text/csv"
+	mimeNDJSON = "application/x-ndjson"
+)
+
+var csvHeader = []string{"delegator", "timestamp", "block", "amount", "new_delegate", "prev_delegate"}
+
+// ExportDelegations streams every delegation matching the filters as csv, the default, or as newline delimited json
+// depending on the Accept header. The rows are written as they are read from the database.
+func (ctr *Controller) ExportDelegations(ctx *gin.Context) {
+	var filter db.DelegationsFilter
+	if value, ok := ctx.Get("filter"); ok {
+		filter = value.(db.DelegationsFilter)
+	}
+
+	format := ctx.NegotiateFormat(mimeCSV, mimeNDJSON)
+	var writer exportWriter
+	switch format {
+	case mimeCSV:
+		writer = &csvExportWriter{csv: csv.NewWriter(ctx.Writer)}
+	case mimeNDJSON:
+		writer = &ndjsonExportWriter{encoder: json.NewEncoder(ctx.Writer)}
+	default:
+		ctx.JSON(http.StatusNotAcceptable, gin.H{"error": "Export is available as text/csv or application/x-ndjson"})
+		return
+	}
+
+	// the status is only sent with the first rows, an error before them can still be answered with a 500
+	started := false
+	start := func() error {
+		started = true
+		extension := "csv"
+		if format == mimeNDJSON {
+			extension = "ndjson"
+		}
+		ctx.Header("Content-Type", format)
+		ctx.Header("Content-Disposition", `attachment; filename="delegations.`+extension+`"`)
+		ctx.Status(http.StatusOK)
+		return writer.header()
+	}
+	err := ctr.db.ExportDelegations(ctx.Request.Context(), filter, func(delegations []db.Delegations) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := writer.write(delegations); err != nil {
+			return err
+		}
+		ctx.Writer.Flush()
+		return nil
+	})
+	// an export without delegations still has the header of the csv
+	if err == nil && !started {
+		err = start()
+	}
+	if err != nil {
+		if !started {
+			ctx.JSON(500, gin.H{"error": "Internal server error"})
+			return
+		}
+		// aborting the connection tells the client the file is truncated, a complete response would hide it
+		log.Errorf("Export of delegations failed after it started: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportWriter writes the delegations of an export in one format
+type exportWriter interface {
+	header() error
+	write(delegations []db.Delegations) error
+}
+
+type csvExportWriter struct {
+	csv *csv.Writer
+}
+
+func (w *csvExportWriter) header() error {
+	w.csv.Write(csvHeader)
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *csvExportWriter) write(delegations []db.Delegations) error {
+	for _, delegation := range delegations {
+		w.csv.Write([]string{
+			delegation.Delegator,
+			delegation.Timestamp.UTC().Format(time.RFC3339),
+			strconv.FormatInt(int64(delegation.Block), 10),
+			strconv.FormatInt(delegation.Amount, 10),
+			delegation.NewDelegate,
+			delegation.PrevDelegate,
+		})
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonExportWriter) header() error {
+	return nil
+}
+
+func (w *ndjsonExportWriter) write(delegations []db.Delegations) error {
+	for _, delegation := range toDelegationsResponse(delegations, len(delegations)).Delegations {
+		// Encode ends each delegation with a newline
+		if err := w.encoder.Encode(delegation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
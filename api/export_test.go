@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/middlewares"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+func newExportRouter(database db.DBInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	controller := NewController(database, nil)
+	r := gin.New()
+	r.GET("/delegations/export", middlewares.ValidationHandler(), controller.ExportDelegations)
+	return r
+}
+
+func exportBatches() [][]db.Delegations {
+	timestamp := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return [][]db.Delegations{
+		{
+			{ID: 3, Delegator: testDelegator, Timestamp: timestamp, Block: 30, Amount: 300, NewDelegate: testBaker, PrevDelegate: "tz1first"},
+			{ID: 2, Delegator: testDelegator, Timestamp: timestamp.Add(-time.Hour), Block: 20, Amount: 200, NewDelegate: "tz1first"},
+		},
+		{
+			{ID: 1, Delegator: "tz1other", Timestamp: timestamp.Add(-2 * time.Hour), Block: 10, Amount: 100, PrevDelegate: testBaker},
+		},
+	}
+}
+
+func TestExportDelegationsCSV(t *testing.T) {
+	mockDB := &MockDB{ExportBatches: exportBatches()}
+	r := newExportRouter(mockDB)
+
+	req := httptest.NewRequest("GET", "/delegations/export?min_amount=100", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "text/csv" {
+		t.Errorf("expected csv by default, got %s", contentType)
+	}
+	if disposition := w.Header().Get("Content-Disposition"); !strings.Contains(disposition, "delegations.csv") {
+		t.Errorf("expected a csv attachment, got %s", disposition)
+	}
+	if mockDB.Filter.MinAmount == nil || *mockDB.Filter.MinAmount != 100 {
+		t.Errorf("expected the filters of the query, got %+v", mockDB.Filter)
+	}
+
+	expected := "delegator,timestamp,block,amount,new_delegate,prev_delegate\n" +
+		testDelegator + ",2024-03-01T12:00:00Z,30,300," + testBaker + ",tz1first\n" +
+		testDelegator + ",2024-03-01T11:00:00Z,20,200,tz1first,\n" +
+		"tz1other,2024-03-01T10:00:00Z,10,100,," + testBaker + "\n"
+	if w.Body.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, w.Body.String())
+	}
+}
+
+func TestExportDelegationsNDJSON(t *testing.T) {
+	r := newExportRouter(&MockDB{ExportBatches: exportBatches()})
+
+	req := httptest.NewRequest("GET", "/delegations/export", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Errorf("expected ndjson, got %s", contentType)
+	}
+
+	var blocks []int32
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var delegation types.Delegation
+		if err := json.Unmarshal(scanner.Bytes(), &delegation); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		blocks = append(blocks, delegation.Block)
+	}
+	if len(blocks) != 3 || blocks[0] != 30 || blocks[2] != 10 {
+		t.Errorf("expected the delegations of blocks 30, 20 and 10, got %v", blocks)
+	}
+}
+
+func TestExportDelegationsEmpty(t *testing.T) {
+	r := newExportRouter(&MockDB{})
+
+	req := httptest.NewRequest("GET", "/delegations/export", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if w.Body.String() != "delegator,timestamp,block,amount,new_delegate,prev_delegate\n" {
+		t.Errorf("expected only the csv header, got %q", w.Body.String())
+	}
+}
+
+func TestExportDelegationsNotAcceptable(t *testing.T) {
+	r := newExportRouter(&MockDB{})
+
+	req := httptest.NewRequest("GET", "/delegations/export", nil)
+	req.Header.Set("Accept", "application/xml")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("expected status 406, got %d", w.Code)
+	}
+}
+
+func TestExportDelegationsError(t *testing.T) {
+	r := newExportRouter(&MockDBError{ExportErr: errors.New("test error")})
+
+	req := httptest.NewRequest("GET", "/delegations/export", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 500 {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+}
+
+func TestExportDelegationsErrorAfterStart(t *testing.T) {
+	r := newExportRouter(&MockDBError{ExportBatches: exportBatches()[:1], ExportErr: errors.New("test error")})
+
+	req := httptest.NewRequest("GET", "/delegations/export", nil)
+	w := httptest.NewRecorder()
+	defer func() {
+		// the connection is aborted so that the client doesn't take the truncated file for a complete one
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("expected the handler to be aborted, got %v", recovered)
+		}
+		if !strings.HasPrefix(w.Body.String(), "delegator,") {
+			t.Errorf("expected the rows sent before the error, got %q", w.Body.String())
+		}
+	}()
+	r.ServeHTTP(w, req)
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ibraheemacara/tezos-delegation-service/broker"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/middlewares"
+	pb "github.com/ibraheemacara/tezos-delegation-service/proto/delegationspb"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	"github.com/ibraheemacara/tezos-delegation-service/utils"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcServer serves the delegations over gRPC from the same database and live feed as the REST api
+type grpcServer struct {
+	pb.UnimplementedDelegationsServiceServer
+	db     db.DBInterface
+	broker *broker.Broker
+}
+
+// NewGrpcServer returns a gRPC server with the delegations service and a logging of every call
+func NewGrpcServer(db db.DBInterface, broker *broker.Broker) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(logUnaryCall),
+		grpc.ChainStreamInterceptor(logStreamCall),
+	)
+	pb.RegisterDelegationsServiceServer(server, &grpcServer{db: db, broker: broker})
+	return server
+}
+
+// ListDelegations returns a page of the delegations matching the filters, from the newest to the oldest
+func (s *grpcServer) ListDelegations(ctx context.Context, req *pb.ListDelegationsRequest) (*pb.ListDelegationsResponse, error) {
+	filter, err := toDelegationsFilter(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = middlewares.DefaultLimit
+	} else if limit < 1 || limit > middlewares.MaxLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", middlewares.MaxLimit)
+	}
+	var cursor *db.Cursor
+	if req.GetCursor() != "" {
+		block, id, err := utils.DecodeCursor(req.GetCursor())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "cursor is invalid")
+		}
+		cursor = &db.Cursor{Block: block, ID: id}
+	}
+
+	// one extra row is fetched to know if there is a next page
+	delegations, err := s.db.GetDelegations(ctx, filter, cursor, limit+1)
+	if err != nil {
+		log.Errorf("Failed to list the delegations: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	page := toDelegationsResponse(delegations, limit)
+	response := &pb.ListDelegationsResponse{Delegations: make([]*pb.Delegation, len(page.Delegations)), NextCursor: page.NextCursor}
+	for i, delegation := range page.Delegations {
+		response.Delegations[i] = toPbDelegation(delegation)
+	}
+	return response, nil
+}
+
+// GetDelegator returns the current delegate of the delegator and the history of its delegations
+func (s *grpcServer) GetDelegator(ctx context.Context, req *pb.GetDelegatorRequest) (*pb.DelegatorTimeline, error) {
+	if !utils.IsValidAddress(req.GetAddress()) {
+		return nil, status.Error(codes.InvalidArgument, "address must be a valid tz1, tz2, tz3 or KT1 address")
+	}
+	history, err := s.db.GetDelegatorHistory(ctx, req.GetAddress())
+	if err != nil {
+		log.Errorf("Failed to get the history of delegator %s: %v", req.GetAddress(), err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	if len(history) == 0 {
+		return nil, status.Error(codes.NotFound, "delegator not found")
+	}
+
+	timeline := toDelegatorTimeline(req.GetAddress(), history)
+	response := &pb.DelegatorTimeline{
+		Delegator:       timeline.Delegator,
+		CurrentDelegate: timeline.CurrentDelegate,
+		SinceBlock:      timeline.SinceBlock,
+		Amount:          timeline.Amount,
+		History:         make([]*pb.Delegation, len(timeline.History)),
+	}
+	if timeline.DelegatingSince != nil {
+		response.DelegatingSince = timestamppb.New(*timeline.DelegatingSince)
+	}
+	for i, delegation := range timeline.History {
+		response.History[i] = toPbDelegation(delegation)
+	}
+	return response, nil
+}
+
+// WatchDelegations sends the newly ingested delegations matching the filters until the client leaves.
+// The stream ends with UNAVAILABLE when the subscriber is dropped or the broker closed.
+func (s *grpcServer) WatchDelegations(req *pb.WatchDelegationsRequest, stream grpc.ServerStreamingServer[pb.Delegation]) error {
+	for _, param := range []struct{ name, address string }{{"delegator", req.GetDelegator()}, {"baker", req.GetBaker()}} {
+		if param.address != "" && !utils.IsValidAddress(param.address) {
+			return status.Errorf(codes.InvalidArgument, "%s must be a valid tz1, tz2, tz3 or KT1 address", param.name)
+		}
+	}
+	if req.GetMinAmount() < 0 {
+		return status.Error(codes.InvalidArgument, "min_amount must not be negative")
+	}
+
+	subscription := s.broker.Subscribe(broker.Filter{Delegator: req.GetDelegator(), Baker: req.GetBaker(), MinAmount: req.GetMinAmount()})
+	defer s.broker.Unsubscribe(subscription)
+	// the headers tell the client that no delegation published from now on will be missed
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case delegation, ok := <-subscription.Events():
+			if !ok {
+				return status.Error(codes.Unavailable, "the delegations stream ended")
+			}
+			if err := stream.Send(toPbDelegation(delegation)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// toDelegationsFilter reads the filters of the request and checks them with DelegationsFilter.Validate, like the REST api
+func toDelegationsFilter(req *pb.ListDelegationsRequest) (db.DelegationsFilter, error) {
+	filter := db.DelegationsFilter{
+		BlockFrom: req.BlockFrom,
+		BlockTo:   req.BlockTo,
+		MinAmount: req.MinAmount,
+		MaxAmount: req.MaxAmount,
+	}
+	if req.Year != nil {
+		// a year of 0 means any year to the filter, it must be rejected here
+		if req.GetYear() == 0 {
+			return filter, fmt.Errorf("year must be %d or later", db.MinYear)
+		}
+		filter.Year = int(req.GetYear())
+	}
+	for _, bound := range []struct {
+		name  string
+		value *timestamppb.Timestamp
+		time  *time.Time
+	}{{"from", req.GetFrom(), &filter.From}, {"to", req.GetTo(), &filter.To}} {
+		if bound.value == nil {
+			continue
+		}
+		if err := bound.value.CheckValid(); err != nil {
+			return filter, fmt.Errorf("%s is invalid", bound.name)
+		}
+		*bound.time = bound.value.AsTime()
+	}
+	return filter, filter.Validate()
+}
+
+func toPbDelegation(delegation types.Delegation) *pb.Delegation {
+	return &pb.Delegation{
+		Delegator:    delegation.Delegator,
+		Timestamp:    timestamppb.New(delegation.Timestamp),
+		Block:        delegation.Block,
+		Amount:       delegation.Amount,
+		NewDelegate:  delegation.NewDelegate,
+		PrevDelegate: delegation.PrevDelegate,
+	}
+}
+
+// logUnaryCall logs the calls like LoggerHandler logs the requests of the REST api
+func logUnaryCall(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(info.FullMethod, start, err)
+	return resp, err
+}
+
+func logStreamCall(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	logCall(info.FullMethod, start, err)
+	return err
+}
+
+func logCall(method string, start time.Time, err error) {
+	log.Infof("gRPC call %s ended with %s in %s", method, status.Code(err), time.Since(start))
+}
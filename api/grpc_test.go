@@ -0,0 +1,236 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ibraheemacara/tezos-delegation-service/broker"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	pb "github.com/ibraheemacara/tezos-delegation-service/proto/delegationspb"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	"github.com/ibraheemacara/tezos-delegation-service/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newGrpcClient serves the gRPC api in memory and returns a client connected to it
+func newGrpcClient(t *testing.T, database db.DBInterface, delegationsBroker *broker.Broker) pb.DelegationsServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := NewGrpcServer(database, delegationsBroker)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewDelegationsServiceClient(conn)
+}
+
+func TestGrpcListDelegations(t *testing.T) {
+	timestamp := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mockDB := &MockDB{DelegationsToGet: []db.Delegations{
+		{ID: 3, Delegator: testDelegator, Block: 30, Timestamp: timestamp, Amount: 300, NewDelegate: testBaker},
+		{ID: 2, Delegator: testDelegator, Block: 20, Timestamp: timestamp, Amount: 200, NewDelegate: testBaker},
+		{ID: 1, Delegator: testDelegator, Block: 10, Timestamp: timestamp, Amount: 100, NewDelegate: testBaker},
+	}}
+	client := newGrpcClient(t, mockDB, broker.NewBroker())
+
+	resp, err := client.ListDelegations(context.Background(), &pb.ListDelegationsRequest{
+		Year:      proto.Int32(2024),
+		From:      timestamppb.New(timestamp),
+		BlockFrom: proto.Int32(5),
+		MinAmount: proto.Int64(0),
+		Limit:     2,
+		Cursor:    utils.EncodeCursor(40, 4),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filter := mockDB.Filter
+	if filter.Year != 2024 || !filter.From.Equal(timestamp) || !filter.To.IsZero() || filter.BlockFrom == nil || *filter.BlockFrom != 5 ||
+		filter.BlockTo != nil || filter.MinAmount == nil || *filter.MinAmount != 0 || filter.MaxAmount != nil {
+		t.Errorf("unexpected filter %+v", filter)
+	}
+	if mockDB.Cursor == nil || *mockDB.Cursor != (db.Cursor{Block: 40, ID: 4}) || mockDB.Limit != 3 {
+		t.Errorf("expected the page after block 40 with one extra row, got cursor %v and limit %d", mockDB.Cursor, mockDB.Limit)
+	}
+	if len(resp.Delegations) != 2 || resp.Delegations[0].Block != 30 || !resp.Delegations[0].Timestamp.AsTime().Equal(timestamp) {
+		t.Errorf("expected the delegations of blocks 30 and 20, got %v", resp.Delegations)
+	}
+	if resp.NextCursor != utils.EncodeCursor(20, 2) {
+		t.Errorf("expected the next cursor to point after block 20, got %q", resp.NextCursor)
+	}
+}
+
+func TestGrpcListDelegationsDefaultLimit(t *testing.T) {
+	mockDB := &MockDB{}
+	resp, err := newGrpcClient(t, mockDB, broker.NewBroker()).ListDelegations(context.Background(), &pb.ListDelegationsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mockDB.Limit != 51 || mockDB.Cursor != nil || mockDB.Filter.Year != 0 {
+		t.Errorf("expected the first page of 50 delegations of any year, got limit %d, cursor %v and filter %+v", mockDB.Limit, mockDB.Cursor, mockDB.Filter)
+	}
+	if len(resp.Delegations) != 0 || resp.NextCursor != "" {
+		t.Errorf("expected an empty last page, got %v", resp)
+	}
+}
+
+func TestGrpcListDelegationsInvalidArguments(t *testing.T) {
+	client := newGrpcClient(t, &MockDB{}, broker.NewBroker())
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for name, req := range map[string]*pb.ListDelegationsRequest{
+		"negative limit":        {Limit: -1},
+		"limit too high":        {Limit: 1001},
+		"invalid cursor":        {Cursor: "invalid"},
+		"zero year":             {Year: proto.Int32(0)},
+		"year before 2018":      {Year: proto.Int32(2017)},
+		"negative block_from":   {BlockFrom: proto.Int32(-1)},
+		"negative block_to":     {BlockTo: proto.Int32(-1)},
+		"negative min_amount":   {MinAmount: proto.Int64(-1)},
+		"negative max_amount":   {MaxAmount: proto.Int64(-1)},
+		"from after to":         {From: timestamppb.New(march), To: timestamppb.New(march.Add(-time.Hour))},
+		"outside the year":      {Year: proto.Int32(2023), From: timestamppb.New(march)},
+		"reversed block range":  {BlockFrom: proto.Int32(20), BlockTo: proto.Int32(10)},
+		"reversed amount range": {MinAmount: proto.Int64(20), MaxAmount: proto.Int64(10)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := client.ListDelegations(context.Background(), req)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGrpcListDelegationsDBError(t *testing.T) {
+	client := newGrpcClient(t, &MockDBError{GetDelegationsError: errors.New("db error")}, broker.NewBroker())
+	_, err := client.ListDelegations(context.Background(), &pb.ListDelegationsRequest{})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("expected Internal, got %v", err)
+	}
+}
+
+func TestGrpcGetDelegator(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mockDB := &MockDB{History: []db.Delegations{
+		{ID: 1, Delegator: testDelegator, Block: 10, Timestamp: start, Amount: 100, NewDelegate: testBaker},
+		{ID: 2, Delegator: testDelegator, Block: 20, Timestamp: start.Add(time.Hour), Amount: 200, NewDelegate: testBaker, PrevDelegate: testBaker},
+	}}
+	timeline, err := newGrpcClient(t, mockDB, broker.NewBroker()).GetDelegator(context.Background(), &pb.GetDelegatorRequest{Address: testDelegator})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mockDB.Delegator != testDelegator || timeline.Delegator != testDelegator || timeline.CurrentDelegate != testBaker {
+		t.Errorf("expected %s delegating to %s, got %v", testDelegator, testBaker, timeline)
+	}
+	if timeline.DelegatingSince == nil || !timeline.DelegatingSince.AsTime().Equal(start) || timeline.SinceBlock != 10 || timeline.Amount != 100 {
+		t.Errorf("expected delegating since block 10 with 100, got %v at block %d with %d", timeline.DelegatingSince, timeline.SinceBlock, timeline.Amount)
+	}
+	if len(timeline.History) != 2 || timeline.History[1].PrevDelegate != testBaker {
+		t.Errorf("expected the history of 2 delegations, got %v", timeline.History)
+	}
+}
+
+func TestGrpcGetDelegatorUndelegated(t *testing.T) {
+	mockDB := &MockDB{History: []db.Delegations{
+		{ID: 1, Delegator: testDelegator, Block: 10, Amount: 100, NewDelegate: testBaker},
+		{ID: 2, Delegator: testDelegator, Block: 20, Amount: 100, PrevDelegate: testBaker},
+	}}
+	timeline, err := newGrpcClient(t, mockDB, broker.NewBroker()).GetDelegator(context.Background(), &pb.GetDelegatorRequest{Address: testDelegator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timeline.CurrentDelegate != "" || timeline.DelegatingSince != nil {
+		t.Errorf("expected no current delegate, got %v", timeline)
+	}
+}
+
+func TestGrpcGetDelegatorErrors(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		database db.DBInterface
+		address  string
+		code     codes.Code
+	}{
+		{"invalid address", &MockDB{}, "tz1invalid", codes.InvalidArgument},
+		{"never delegated", &MockDB{}, testDelegator, codes.NotFound},
+		{"db error", &MockDBError{HistoryErr: errors.New("db error")}, testDelegator, codes.Internal},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newGrpcClient(t, test.database, broker.NewBroker()).GetDelegator(context.Background(), &pb.GetDelegatorRequest{Address: test.address})
+			if status.Code(err) != test.code {
+				t.Errorf("expected %v, got %v", test.code, err)
+			}
+		})
+	}
+}
+
+func TestGrpcWatchDelegations(t *testing.T) {
+	delegationsBroker := broker.NewBroker()
+	client := newGrpcClient(t, &MockDB{}, delegationsBroker)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchDelegations(ctx, &pb.WatchDelegationsRequest{Baker: testBaker, MinAmount: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the headers are sent once the stream is subscribed
+	if _, err := stream.Header(); err != nil {
+		t.Fatal(err)
+	}
+
+	delegationsBroker.Publish([]types.Delegation{
+		{Delegator: testDelegator, NewDelegate: testBaker, Amount: 10, Block: 1},
+		{Delegator: testDelegator, NewDelegate: "tz1other", Amount: 1000, Block: 2},
+		{Delegator: testDelegator, NewDelegate: testBaker, Amount: 1000, Block: 3},
+	})
+	delegation, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delegation.Block != 3 || delegation.NewDelegate != testBaker || delegation.Amount != 1000 {
+		t.Errorf("expected the delegation of block 3, got %v", delegation)
+	}
+
+	// closing the broker ends the stream
+	delegationsBroker.Close()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected the stream to end with Unavailable, got %v", err)
+	}
+}
+
+func TestGrpcWatchDelegationsInvalidArguments(t *testing.T) {
+	client := newGrpcClient(t, &MockDB{}, broker.NewBroker())
+	for name, req := range map[string]*pb.WatchDelegationsRequest{
+		"invalid delegator":   {Delegator: "tz1invalid"},
+		"invalid baker":       {Baker: "invalid"},
+		"negative min amount": {MinAmount: -1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			stream, err := client.WatchDelegations(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+}
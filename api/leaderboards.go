@@ -0,0 +1,55 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/middlewares"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	"github.com/ibraheemacara/tezos-delegation-service/utils"
+)
+
+// GetDelegatorsLeaderboard ranks the delegators by the amount or the number of their delegations
+func (ctr *Controller) GetDelegatorsLeaderboard(ctx *gin.Context) {
+	ctr.getLeaderboard(ctx, db.LeaderboardDelegators)
+}
+
+// GetBakersLeaderboard ranks the bakers by the amount or the number of the delegations they received
+func (ctr *Controller) GetBakersLeaderboard(ctx *gin.Context) {
+	ctr.getLeaderboard(ctx, db.LeaderboardBakers)
+}
+
+func (ctr *Controller) getLeaderboard(ctx *gin.Context, role string) {
+	query := db.LeaderboardQuery{OrderBy: db.LeaderboardByAmount, Limit: middlewares.DefaultLimit}
+	if value, ok := ctx.Get("leaderboard"); ok {
+		query = value.(db.LeaderboardQuery)
+	}
+	query.Role = role
+
+	// one extra entry is fetched to know if there is a next page
+	limit := query.Limit
+	query.Limit++
+	entries, err := ctr.db.GetLeaderboard(ctx.Request.Context(), query)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	response := types.LeaderboardResponse{Entries: []types.LeaderboardEntry{}}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		value := last.TotalAmount
+		if query.OrderBy == db.LeaderboardByCount {
+			value = last.Count
+		}
+		response.NextCursor = utils.EncodeLeaderboardCursor(value, last.Address)
+	}
+	for _, entry := range entries {
+		response.Entries = append(response.Entries, types.LeaderboardEntry{
+			Address:     entry.Address,
+			Count:       entry.Count,
+			TotalAmount: entry.TotalAmount,
+		})
+	}
+	ctx.JSON(200, response)
+}
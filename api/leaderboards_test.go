@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/middlewares"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	"github.com/ibraheemacara/tezos-delegation-service/utils"
+)
+
+func newLeaderboardsRouter(t *testing.T, database db.DBInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	controller := NewController(database, nil)
+	validate := openAPIValidator(t)
+	r := gin.New()
+	r.GET("/leaderboards/delegators", validate, middlewares.LeaderboardValidationHandler(), controller.GetDelegatorsLeaderboard)
+	r.GET("/leaderboards/bakers", validate, middlewares.LeaderboardValidationHandler(), controller.GetBakersLeaderboard)
+	r.GET("/leaderboards/bakers/:year", validate, middlewares.LeaderboardValidationHandler(), controller.GetBakersLeaderboard)
+	return r
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	mockDB := &MockDB{Leaderboard: []db.LeaderboardEntry{
+		{Address: testBaker, Count: 10, TotalAmount: 3000},
+		{Address: testDelegator, Count: 20, TotalAmount: 2000},
+		{Address: "tz1other", Count: 1, TotalAmount: 1000},
+	}}
+	r := newLeaderboardsRouter(t, mockDB)
+
+	req := httptest.NewRequest("GET", "/leaderboards/bakers/2024?limit=2&from=2024-03-01&to=2024-04-01", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	query := mockDB.LeaderboardQuery
+	if query.Role != db.LeaderboardBakers || query.OrderBy != db.LeaderboardByAmount || query.Year != 2024 || query.Limit != 3 || query.Cursor != nil {
+		t.Errorf("unexpected leaderboard query %+v", query)
+	}
+	if !query.From.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) || !query.To.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected days [%v, %v[", query.From, query.To)
+	}
+
+	var response types.LeaderboardResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Entries) != 2 || response.Entries[0].Address != testBaker || response.Entries[1].TotalAmount != 2000 {
+		t.Errorf("unexpected leaderboard %+v", response.Entries)
+	}
+	if response.NextCursor != utils.EncodeLeaderboardCursor(2000, testDelegator) {
+		t.Errorf("expected the cursor of the second entry, got %q", response.NextCursor)
+	}
+}
+
+func TestGetLeaderboardByCount(t *testing.T) {
+	mockDB := &MockDB{Leaderboard: []db.LeaderboardEntry{
+		{Address: testDelegator, Count: 20, TotalAmount: 2000},
+		{Address: testBaker, Count: 10, TotalAmount: 3000},
+	}}
+	r := newLeaderboardsRouter(t, mockDB)
+
+	cursor := utils.EncodeLeaderboardCursor(30, testBaker)
+	req := httptest.NewRequest("GET", "/leaderboards/delegators?by=count&limit=1&cursor="+cursor, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	query := mockDB.LeaderboardQuery
+	if query.Role != db.LeaderboardDelegators || query.OrderBy != db.LeaderboardByCount || query.Year != 0 {
+		t.Errorf("unexpected leaderboard query %+v", query)
+	}
+	if query.Cursor == nil || query.Cursor.Value != 30 || query.Cursor.Address != testBaker {
+		t.Errorf("unexpected cursor %+v", query.Cursor)
+	}
+
+	var response types.LeaderboardResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	// the cursor holds the ranked value
+	if response.NextCursor != utils.EncodeLeaderboardCursor(20, testDelegator) {
+		t.Errorf("expected the cursor of the first entry, got %q", response.NextCursor)
+	}
+}
+
+func TestGetLeaderboardEmpty(t *testing.T) {
+	r := newLeaderboardsRouter(t, &MockDB{})
+
+	req := httptest.NewRequest("GET", "/leaderboards/bakers", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != `{"data":[]}` {
+		t.Errorf("expected an empty leaderboard, got %s", w.Body.String())
+	}
+}
+
+func TestGetLeaderboardInvalidParams(t *testing.T) {
+	r := newLeaderboardsRouter(t, &MockDB{})
+
+	for _, path := range []string{
+		"/leaderboards/bakers/2017",
+		"/leaderboards/bakers/abc",
+		"/leaderboards/bakers?by=balance",
+		"/leaderboards/bakers?from=2024-03-01T00:00:00Z",
+		"/leaderboards/bakers?from=2024-03-01&to=2024-03-01",
+		"/leaderboards/bakers?limit=0",
+		"/leaderboards/bakers?cursor=invalid",
+		"/leaderboards/bakers?cursor=" + utils.EncodeCursor(1, 2),
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestGetLeaderboardError(t *testing.T) {
+	r := newLeaderboardsRouter(t, &MockDBError{LeaderboardErr: errors.New("test error")})
+
+	req := httptest.NewRequest("GET", "/leaderboards/delegators", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 500 {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+}
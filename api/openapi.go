@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// LoadSpec parses the OpenAPI specification of the api and checks it is valid
+func LoadSpec() (*openapi3.T, error) {
+	spec, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		return nil, err
+	}
+	if err := spec.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// serveSpec answers with the specification in json, it is encoded once
+func serveSpec(spec *openapi3.T) (gin.HandlerFunc, error) {
+	body, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "application/json", body)
+	}, nil
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/config"
+	"github.com/ibraheemacara/tezos-delegation-service/middlewares"
+	"github.com/ibraheemacara/tezos-delegation-service/utils"
+)
+
+// openAPIValidator validates the requests of the tests against the specification of the api
+func openAPIValidator(t *testing.T) gin.HandlerFunc {
+	t.Helper()
+	spec, err := LoadSpec()
+	if err != nil {
+		t.Fatalf("failed to load the OpenAPI specification: %v", err)
+	}
+	return middlewares.OpenAPIValidationHandler(spec)
+}
+
+func TestRoutesAreDescribed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	spec, err := LoadSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	var cfg config.Config
+	cfg.Admin.Token = "token"
+	if err := registerRoutes(engine, cfg, NewController(&MockDB{}, nil), spec); err != nil {
+		t.Fatal(err)
+	}
+
+	described := 0
+	for _, path := range spec.Paths.InMatchingOrder() {
+		described += len(spec.Paths.Value(path).Operations())
+	}
+	routes := engine.Routes()
+	for _, route := range routes {
+		path := route.Path
+		for _, segment := range strings.Split(path, "/") {
+			if name, ok := strings.CutPrefix(segment, ":"); ok {
+				path = strings.Replace(path, segment, "{"+name+"}", 1)
+			}
+		}
+		if item := spec.Paths.Value(path); item == nil || item.GetOperation(route.Method) == nil {
+			t.Errorf("%s %s is missing from the specification", route.Method, path)
+		}
+	}
+	if described != len(routes) {
+		t.Errorf("expected the %d operations of the specification to be served, got %d routes", described, len(routes))
+	}
+}
+
+func TestServeSpec(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	spec, err := LoadSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	getSpec, err := serveSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/openapi.json", middlewares.OpenAPIValidationHandler(spec), getSpec)
+
+	req := httptest.NewRequest("GET", "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("expected json, got %s", contentType)
+	}
+	var document struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(document.OpenAPI, "3.") {
+		t.Errorf("expected an OpenAPI 3 document, got %q", document.OpenAPI)
+	}
+	if _, ok := document.Components.Schemas["DelegationsResponse"]; !ok {
+		t.Errorf("expected the DelegationsResponse schema, got %v", document.Components.Schemas)
+	}
+}
+
+func TestOpenAPIValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	validate := openAPIValidator(t)
+	controller := NewController(&MockDB{}, nil)
+
+	r := gin.New()
+	r.GET("/delegations/:year", validate, middlewares.ValidationHandler(), controller.GetDelegations)
+	r.GET("/leaderboards/bakers", validate, middlewares.LeaderboardValidationHandler(), controller.GetBakersLeaderboard)
+	r.POST("/admin/webhooks", validate, middlewares.WebhookValidationHandler(), controller.CreateWebhook)
+	r.GET("/undescribed", validate, controller.GetDelegations)
+
+	for _, test := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/delegations/2024?limit=10&cursor=" + utils.EncodeCursor(10, 1) + "&from=2024-03-01&delegator=ignored", "", 200},
+		{"GET", "/delegations/2024?cursor=", "", 200},
+		{"GET", "/delegations/2017", "", 400},
+		{"GET", "/delegations/twenty", "", 400},
+		{"GET", "/delegations/2024?cursor=invalid", "", 400},
+		{"GET", "/delegations/2024?to=2024-03-01T25:00:00Z", "", 400},
+		{"GET", "/delegations/2024?max_amount=99999999999999999999", "", 400},
+		{"GET", "/leaderboards/bakers?by=amount&from=2024-01-01", "", 200},
+		{"GET", "/leaderboards/bakers?by=size", "", 400},
+		{"GET", "/leaderboards/bakers?cursor=" + utils.EncodeCursor(10, 1), "", 400},
+		{"POST", "/admin/webhooks", `{"url":"https://example.com/hook","baker":"` + testBaker + `","min_amount":10}`, 201},
+		{"POST", "/admin/webhooks", `{"baker":"` + testBaker + `"}`, 400},
+		{"POST", "/admin/webhooks", `{"url":"https://example.com/hook","min_amount":"ten"}`, 400},
+		{"POST", "/admin/webhooks", `{"url":"https://example.com/hook","delegator":"tz1invalid"}`, 400},
+		{"GET", "/undescribed", "", 500},
+	} {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != test.status {
+			t.Errorf("expected status %d for %s %s, got %d: %s", test.status, test.method, test.path, w.Code, w.Body.String())
+		}
+		if test.status == http.StatusBadRequest && !strings.Contains(w.Body.String(), `"error"`) {
+			t.Errorf("expected an error for %s %s, got %s", test.method, test.path, w.Body.String())
+		}
+	}
+}
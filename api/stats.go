@@ -0,0 +1,43 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+// statsPeriodLayouts formats the start of a period according to the interval
+var statsPeriodLayouts = map[string]string{
+	db.StatsIntervalYear:  "2006",
+	db.StatsIntervalMonth: "2006-01",
+	db.StatsIntervalDay:   "2006-01-02",
+}
+
+// GetDelegationStats returns the count, the total and average amount and the unique delegators of the delegations by period
+func (ctr *Controller) GetDelegationStats(ctx *gin.Context) {
+	query := db.StatsQuery{Interval: db.StatsIntervalMonth}
+	if value, ok := ctx.Get("stats"); ok {
+		query = value.(db.StatsQuery)
+	}
+
+	stats, err := ctr.db.GetDelegationStats(ctx.Request.Context(), query)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	response := types.DelegationStatsResponse{Interval: query.Interval, Stats: make([]types.DelegationStats, len(stats))}
+	for i, stat := range stats {
+		response.Stats[i] = types.DelegationStats{
+			Period:           stat.Period.UTC().Format(statsPeriodLayouts[query.Interval]),
+			Count:            stat.Count,
+			TotalAmount:      stat.TotalAmount,
+			AverageAmount:    stat.AverageAmount,
+			UniqueDelegators: stat.UniqueDelegators,
+		}
+		if query.ByBaker {
+			response.Stats[i].Baker = &stat.Baker
+		}
+	}
+	ctx.JSON(200, response)
+}
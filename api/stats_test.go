@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/middlewares"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+func newStatsRouter(t *testing.T, database db.DBInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	controller := NewController(database, nil)
+	r := gin.New()
+	r.GET("/stats/delegations", openAPIValidator(t), middlewares.StatsValidationHandler(), controller.GetDelegationStats)
+	return r
+}
+
+func TestGetDelegationStats(t *testing.T) {
+	mockDB := &MockDB{Stats: []db.DelegationStats{
+		{Period: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Baker: testBaker, Count: 3, TotalAmount: 600, AverageAmount: 200, UniqueDelegators: 2},
+		{Period: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), Baker: "", Count: 1, TotalAmount: 10, AverageAmount: 10, UniqueDelegators: 1},
+	}}
+	r := newStatsRouter(t, mockDB)
+
+	req := httptest.NewRequest("GET", "/stats/delegations?by_baker=true&from=2024-01-01&to=2025-01-01T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	query := mockDB.StatsQuery
+	if query.Interval != db.StatsIntervalMonth || !query.ByBaker || !query.From.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !query.To.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected stats query %+v", query)
+	}
+
+	var response types.DelegationStatsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Interval != "month" || len(response.Stats) != 2 {
+		t.Fatalf("unexpected response %+v", response)
+	}
+	first := response.Stats[0]
+	if first.Period != "2024-03" || first.Baker == nil || *first.Baker != testBaker || first.Count != 3 || first.TotalAmount != 600 || first.AverageAmount != 200 || first.UniqueDelegators != 2 {
+		t.Errorf("unexpected stats %+v", first)
+	}
+	// the delegations removing a delegate are grouped under an empty baker
+	if second := response.Stats[1]; second.Baker == nil || *second.Baker != "" {
+		t.Errorf("expected an empty baker, got %+v", second)
+	}
+}
+
+func TestGetDelegationStatsInterval(t *testing.T) {
+	mockDB := &MockDB{Stats: []db.DelegationStats{{Period: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), Count: 1}}}
+	r := newStatsRouter(t, mockDB)
+
+	req := httptest.NewRequest("GET", "/stats/delegations?interval=day", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var response types.DelegationStatsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Stats) != 1 || response.Stats[0].Period != "2024-03-15" || response.Stats[0].Baker != nil {
+		t.Errorf("unexpected response %s", w.Body.String())
+	}
+	if !mockDB.StatsQuery.From.IsZero() || !mockDB.StatsQuery.To.IsZero() {
+		t.Errorf("expected no time bounds, got %+v", mockDB.StatsQuery)
+	}
+}
+
+func TestGetDelegationStatsEmpty(t *testing.T) {
+	r := newStatsRouter(t, &MockDB{})
+
+	req := httptest.NewRequest("GET", "/stats/delegations", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != `{"interval":"month","data":[]}` {
+		t.Errorf("expected empty stats, got %s", w.Body.String())
+	}
+}
+
+func TestGetDelegationStatsInvalidParams(t *testing.T) {
+	r := newStatsRouter(t, &MockDB{})
+
+	for _, query := range []string{"interval=week", "by_baker=maybe", "from=yesterday", "to=2024-13-01", "from=2024-02-01&to=2024-01-01"} {
+		req := httptest.NewRequest("GET", "/stats/delegations?"+query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestGetDelegationStatsError(t *testing.T) {
+	r := newStatsRouter(t, &MockDBError{StatsErr: errors.New("test error")})
+
+	req := httptest.NewRequest("GET", "/stats/delegations", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 500 {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/ibraheemacara/tezos-delegation-service/broker"
+	log "github.com/sirupsen/logrus"
+)
+
+// streamHeartbeat is the interval of the keep-alive messages sent on idle streams
+const streamHeartbeat = 15 * time.Second
+
+var upgrader = websocket.Upgrader{}
+
+// StreamDelegations pushes the newly ingested delegations matching the filters as server-sent events,
+// or as json messages when the client asks for a websocket
+func (ctr *Controller) StreamDelegations(ctx *gin.Context) {
+	var filter broker.Filter
+	if value, ok := ctx.Get("filter"); ok {
+		filter = value.(broker.Filter)
+	}
+	if websocket.IsWebSocketUpgrade(ctx.Request) {
+		ctr.websocketDelegations(ctx, filter)
+		return
+	}
+
+	subscription := ctr.broker.Subscribe(filter)
+	defer ctr.broker.Unsubscribe(subscription)
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case delegation, ok := <-subscription.Events():
+			if !ok {
+				return false
+			}
+			ctx.SSEvent("delegation", delegation)
+			return true
+		case <-heartbeat.C:
+			// a comment keeps the idle connections open through proxies
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+func (ctr *Controller) websocketDelegations(ctx *gin.Context, filter broker.Filter) {
+	// subscribed before the upgrade so that no delegation is missed once the client is connected
+	subscription := ctr.broker.Subscribe(filter)
+	defer ctr.broker.Unsubscribe(subscription)
+
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		// the upgrader already answered with an error
+		log.Errorf("Failed to upgrade the delegations stream to a websocket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// reading processes the control frames and tells when the client is gone, the client sends nothing else
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case delegation, ok := <-subscription.Events():
+			if !ok {
+				message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "stream closed")
+				_ = conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(streamHeartbeat))
+			if err := conn.WriteJSON(delegation); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamHeartbeat)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/ibraheemacara/tezos-delegation-service/broker"
+	"github.com/ibraheemacara/tezos-delegation-service/middlewares"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+const (
+	testDelegator = "tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd"
+	testBaker     = "tz1WCd2jm4uSt4vntk4vSuUWoZQGhLcDuR9q"
+)
+
+func newStreamServer(t *testing.T, delegationsBroker *broker.Broker) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	controller := NewController(&MockDB{}, delegationsBroker)
+	r := gin.New()
+	r.GET("/delegations/stream", openAPIValidator(t), middlewares.StreamValidationHandler(), controller.StreamDelegations)
+	return httptest.NewServer(r)
+}
+
+func TestStreamDelegations(t *testing.T) {
+	delegationsBroker := broker.NewBroker()
+	server := newStreamServer(t, delegationsBroker)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/delegations/stream?min_amount=100&baker="+testBaker, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("expected an event stream, got %q", contentType)
+	}
+
+	// the stream is subscribed once connected
+	delegationsBroker.Publish([]types.Delegation{
+		{Delegator: testDelegator, NewDelegate: testBaker, Amount: 10, Block: 1},
+		{Delegator: testDelegator, NewDelegate: "tz1other", Amount: 1000, Block: 2},
+		{Delegator: testDelegator, NewDelegate: testBaker, Amount: 1000, Block: 3},
+	})
+
+	scanner := bufio.NewScanner(resp.Body)
+	var lines []string
+	for scanner.Scan() && len(lines) < 2 {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != 2 || lines[0] != "event:delegation" || !strings.Contains(lines[1], `"block":3`) {
+		t.Errorf("expected the delegation of block 3, got %v", lines)
+	}
+
+	// closing the broker ends the stream
+	delegationsBroker.Close()
+	for scanner.Scan() {
+	}
+	if err := scanner.Err(); err != nil {
+		t.Errorf("expected the stream to end, got %v", err)
+	}
+}
+
+func TestStreamDelegationsWebsocket(t *testing.T) {
+	delegationsBroker := broker.NewBroker()
+	server := newStreamServer(t, delegationsBroker)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/delegations/stream?delegator=" + testDelegator
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// the stream is subscribed once connected
+	delegationsBroker.Publish([]types.Delegation{
+		{Delegator: "tz1other", Block: 1},
+		{Delegator: testDelegator, Block: 2},
+	})
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var delegation types.Delegation
+	if err := conn.ReadJSON(&delegation); err != nil {
+		t.Fatal(err)
+	}
+	if delegation.Block != 2 {
+		t.Errorf("expected the delegation of block 2, got %+v", delegation)
+	}
+
+	delegationsBroker.Close()
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected the stream to be closed, got %v", err)
+	}
+}
+
+func TestStreamDelegationsInvalidFilters(t *testing.T) {
+	server := newStreamServer(t, broker.NewBroker())
+	defer server.Close()
+
+	for _, query := range []string{"delegator=tz1invalid", "baker=abc", "min_amount=-1", "min_amount=ten"} {
+		resp, err := http.Get(server.URL + "/delegations/stream?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	"github.com/ibraheemacara/tezos-delegation-service/webhooks"
+	log "github.com/sirupsen/logrus"
+)
+
+func (ctr *Controller) GetWebhooks(ctx *gin.Context) {
+	list, err := ctr.db.GetWebhooks(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	response := make([]types.Webhook, len(list))
+	for i, webhook := range list {
+		response[i] = toWebhookResponse(webhook)
+	}
+	ctx.JSON(200, response)
+}
+
+func (ctr *Controller) GetWebhook(ctx *gin.Context) {
+	webhook, err := ctr.db.GetWebhook(ctx.Request.Context(), ctx.GetUint("id"))
+	if err != nil {
+		webhookError(ctx, err)
+		return
+	}
+	ctx.JSON(200, toWebhookResponse(webhook))
+}
+
+// CreateWebhook registers a webhook, its secret is only returned in this response
+func (ctr *Controller) CreateWebhook(ctx *gin.Context) {
+	request := ctx.MustGet("webhook").(types.WebhookRequest)
+	webhook, err := fromWebhookRequest(request)
+	if err != nil {
+		log.Errorf("Failed to generate webhook secret: %v", err)
+		ctx.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	if err := ctr.db.CreateWebhook(ctx.Request.Context(), &webhook); err != nil {
+		ctx.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	response := toWebhookResponse(webhook)
+	response.Secret = webhook.Secret
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// UpdateWebhook replaces the webhook, its secret is kept when the request has none
+func (ctr *Controller) UpdateWebhook(ctx *gin.Context) {
+	request := ctx.MustGet("webhook").(types.WebhookRequest)
+	current, err := ctr.db.GetWebhook(ctx.Request.Context(), ctx.GetUint("id"))
+	if err != nil {
+		webhookError(ctx, err)
+		return
+	}
+	if request.Secret == "" {
+		request.Secret = current.Secret
+	}
+
+	webhook, err := fromWebhookRequest(request)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+	webhook.ID = current.ID
+	webhook.CreatedAt = current.CreatedAt
+	if err := ctr.db.UpdateWebhook(ctx.Request.Context(), &webhook); err != nil {
+		webhookError(ctx, err)
+		return
+	}
+	ctx.JSON(200, toWebhookResponse(webhook))
+}
+
+func (ctr *Controller) DeleteWebhook(ctx *gin.Context) {
+	if err := ctr.db.DeleteWebhook(ctx.Request.Context(), ctx.GetUint("id")); err != nil {
+		webhookError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetWebhookDeadLetters returns the payloads that could not be delivered to the webhook
+func (ctr *Controller) GetWebhookDeadLetters(ctx *gin.Context) {
+	id := ctx.GetUint("id")
+	if _, err := ctr.db.GetWebhook(ctx.Request.Context(), id); err != nil {
+		webhookError(ctx, err)
+		return
+	}
+	deadLetters, err := ctr.db.GetWebhookDeadLetters(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	response := make([]types.WebhookDeadLetter, len(deadLetters))
+	for i, deadLetter := range deadLetters {
+		response[i] = types.WebhookDeadLetter{
+			ID:        deadLetter.ID,
+			WebhookID: deadLetter.WebhookID,
+			Payload:   json.RawMessage(deadLetter.Payload),
+			Attempts:  deadLetter.Attempts,
+			Error:     deadLetter.Error,
+			CreatedAt: deadLetter.CreatedAt,
+		}
+	}
+	ctx.JSON(200, response)
+}
+
+// webhookError answers 404 for a webhook that does not exist and 500 otherwise
+func webhookError(ctx *gin.Context, err error) {
+	if errors.Is(err, db.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	ctx.JSON(500, gin.H{"error": "Internal server error"})
+}
+
+// fromWebhookRequest builds the webhook of the request, generating its secret when it has none
+func fromWebhookRequest(request types.WebhookRequest) (db.Webhook, error) {
+	webhook := db.Webhook{
+		Url:       request.Url,
+		Secret:    request.Secret,
+		Delegator: request.Delegator,
+		Baker:     request.Baker,
+		MinAmount: request.MinAmount,
+		Active:    request.Active == nil || *request.Active,
+	}
+	if webhook.Secret == "" {
+		secret, err := webhooks.NewSecret()
+		if err != nil {
+			return db.Webhook{}, err
+		}
+		webhook.Secret = secret
+	}
+	return webhook, nil
+}
+
+func toWebhookResponse(webhook db.Webhook) types.Webhook {
+	return types.Webhook{
+		ID:        webhook.ID,
+		Url:       webhook.Url,
+		Delegator: webhook.Delegator,
+		Baker:     webhook.Baker,
+		MinAmount: webhook.MinAmount,
+		Active:    webhook.Active,
+	}
+}
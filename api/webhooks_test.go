@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/middlewares"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+const testAdminToken = "admin-token"
+
+func newAdminRouter(t *testing.T, database db.DBInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	controller := NewController(database, nil)
+	r := gin.New()
+	admin := r.Group("/admin", middlewares.AdminAuthHandler(testAdminToken), openAPIValidator(t), middlewares.WebhookValidationHandler())
+	admin.GET("/webhooks", controller.GetWebhooks)
+	admin.POST("/webhooks", controller.CreateWebhook)
+	admin.GET("/webhooks/:id", controller.GetWebhook)
+	admin.PUT("/webhooks/:id", controller.UpdateWebhook)
+	admin.DELETE("/webhooks/:id", controller.DeleteWebhook)
+	admin.GET("/webhooks/:id/dead-letters", controller.GetWebhookDeadLetters)
+	return r
+}
+
+func adminRequest(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestWebhooksCRUD(t *testing.T) {
+	mockDB := &MockDB{}
+	r := newAdminRouter(t, mockDB)
+
+	w := adminRequest(r, "POST", "/admin/webhooks", `{"url":"https://example.com/hook","baker":"tz1WCd2jm4uSt4vntk4vSuUWoZQGhLcDuR9q","min_amount":10000000000}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created types.Webhook
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != 1 || !created.Active || created.MinAmount != 10000000000 || len(created.Secret) != 64 {
+		t.Errorf("unexpected webhook %+v", created)
+	}
+
+	// the secret is only returned on creation
+	w = adminRequest(r, "GET", "/admin/webhooks/1", "")
+	if w.Code != 200 || strings.Contains(w.Body.String(), created.Secret) {
+		t.Errorf("expected the webhook without its secret, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(r, "PUT", "/admin/webhooks/1", `{"url":"https://example.com/other","active":false}`)
+	if w.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	updated := mockDB.Webhooks[0]
+	if updated.Url != "https://example.com/other" || updated.Active || updated.Baker != "" || updated.Secret != created.Secret {
+		t.Errorf("unexpected updated webhook %+v", updated)
+	}
+
+	w = adminRequest(r, "GET", "/admin/webhooks", "")
+	var list []types.Webhook
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != 1 {
+		t.Errorf("expected 1 webhook, got %s", w.Body.String())
+	}
+
+	mockDB.DeadLetters = []db.WebhookDeadLetter{{ID: 1, WebhookID: 1, Payload: `{"webhook_id":1}`, Attempts: 5, Error: "timeout"}}
+	w = adminRequest(r, "GET", "/admin/webhooks/1/dead-letters", "")
+	var deadLetters []types.WebhookDeadLetter
+	if err := json.Unmarshal(w.Body.Bytes(), &deadLetters); err != nil || len(deadLetters) != 1 || string(deadLetters[0].Payload) != `{"webhook_id":1}` {
+		t.Errorf("expected 1 dead letter, got %s", w.Body.String())
+	}
+
+	if w = adminRequest(r, "DELETE", "/admin/webhooks/1", ""); w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+	if w = adminRequest(r, "GET", "/admin/webhooks/1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestWebhooksValidation(t *testing.T) {
+	r := newAdminRouter(t, &MockDB{})
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{"POST", "/admin/webhooks", `not json`},
+		{"POST", "/admin/webhooks", `{"url":"ftp://example.com"}`},
+		{"POST", "/admin/webhooks", `{"url":"/relative"}`},
+		{"POST", "/admin/webhooks", `{"url":"https://example.com","delegator":"tz1invalid"}`},
+		{"POST", "/admin/webhooks", `{"url":"https://example.com","min_amount":-1}`},
+		{"GET", "/admin/webhooks/abc", ""},
+		{"DELETE", "/admin/webhooks/0", ""},
+	}
+	for _, test := range tests {
+		if w := adminRequest(r, test.method, test.path, test.body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s %s: expected status 400, got %d", test.method, test.path, test.body, w.Code)
+		}
+	}
+
+	if w := adminRequest(r, "PUT", "/admin/webhooks/7", `{"url":"https://example.com"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestWebhooksUnauthorized(t *testing.T) {
+	r := newAdminRouter(t, &MockDB{})
+
+	for _, header := range []string{"", "Bearer wrong", testAdminToken} {
+		req := httptest.NewRequest("GET", "/admin/webhooks", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("authorization %q: expected status 401, got %d", header, w.Code)
+		}
+	}
+}
+
+func TestWebhooksError(t *testing.T) {
+	r := newAdminRouter(t, &MockDBError{WebhooksErr: errors.New("test error")})
+
+	if w := adminRequest(r, "GET", "/admin/webhooks", ""); w.Code != 500 {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+	if w := adminRequest(r, "POST", "/admin/webhooks", `{"url":"https://example.com"}`); w.Code != 500 {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+}
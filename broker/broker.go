@@ -0,0 +1,116 @@
+package broker
+
+import (
+	"sync"
+
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// subscriptionBuffer is the number of delegations a subscriber can lag behind before it is dropped
+const subscriptionBuffer = 1000
+
+// Filter selects the delegations sent to a subscriber, empty fields match every delegation
+type Filter struct {
+	Delegator string
+	Baker     string
+	MinAmount int64
+}
+
+func (f Filter) Match(delegation types.Delegation) bool {
+	if f.Delegator != "" && delegation.Delegator != f.Delegator {
+		return false
+	}
+	if f.Baker != "" && delegation.NewDelegate != f.Baker {
+		return false
+	}
+	return delegation.Amount >= f.MinAmount
+}
+
+// Subscription receives the published delegations matching its filter
+type Subscription struct {
+	filter Filter
+	events chan types.Delegation
+}
+
+// Events returns the delegations of the subscription, it is closed when the subscriber is dropped or the broker closed
+func (s *Subscription) Events() <-chan types.Delegation {
+	return s.events
+}
+
+// Broker carries the newly ingested delegations from the watcher to the api subscribers.
+// Publishing never blocks the watcher: a subscriber too slow to keep up is dropped.
+type Broker struct {
+	mu            sync.Mutex
+	subscriptions map[*Subscription]struct{}
+	closed        bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscriptions: make(map[*Subscription]struct{})}
+}
+
+// Subscribe registers a subscriber, the subscription of a closed broker has its events already closed
+func (b *Broker) Subscribe(filter Filter) *Subscription {
+	subscription := &Subscription{filter: filter, events: make(chan types.Delegation, subscriptionBuffer)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(subscription.events)
+		return subscription
+	}
+	b.subscriptions[subscription] = struct{}{}
+	return subscription
+}
+
+func (b *Broker) Unsubscribe(subscription *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(subscription)
+}
+
+// Publish sends the delegations to the subscribers they match
+func (b *Broker) Publish(delegations []types.Delegation) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for subscription := range b.subscriptions {
+		if !subscription.send(delegations) {
+			log.Warn("Dropping a delegations subscriber too slow to keep up")
+			b.remove(subscription)
+		}
+	}
+}
+
+// send queues the matching delegations, it returns false when the buffer of the subscription is full
+func (s *Subscription) send(delegations []types.Delegation) bool {
+	for _, delegation := range delegations {
+		if !s.filter.Match(delegation) {
+			continue
+		}
+		select {
+		case s.events <- delegation:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Close ends all the subscriptions, the later ones are closed right away
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for subscription := range b.subscriptions {
+		b.remove(subscription)
+	}
+	b.closed = true
+}
+
+func (b *Broker) remove(subscription *Subscription) {
+	if _, ok := b.subscriptions[subscription]; !ok {
+		return
+	}
+	delete(b.subscriptions, subscription)
+	close(subscription.events)
+}
@@ -0,0 +1,88 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+func TestFilterMatch(t *testing.T) {
+	delegation := types.Delegation{Delegator: "tz1a", NewDelegate: "tz1baker", Amount: 100}
+
+	tests := []struct {
+		filter Filter
+		match  bool
+	}{
+		{Filter{}, true},
+		{Filter{Delegator: "tz1a"}, true},
+		{Filter{Delegator: "tz1b"}, false},
+		{Filter{Baker: "tz1baker"}, true},
+		{Filter{Baker: "tz1other"}, false},
+		{Filter{MinAmount: 100}, true},
+		{Filter{MinAmount: 101}, false},
+		{Filter{Delegator: "tz1a", Baker: "tz1baker", MinAmount: 50}, true},
+	}
+	for _, test := range tests {
+		if match := test.filter.Match(delegation); match != test.match {
+			t.Errorf("filter %+v: expected match %v, got %v", test.filter, test.match, match)
+		}
+	}
+}
+
+func TestPublish(t *testing.T) {
+	broker := NewBroker()
+	all := broker.Subscribe(Filter{})
+	large := broker.Subscribe(Filter{MinAmount: 1000})
+
+	broker.Publish([]types.Delegation{
+		{Delegator: "tz1a", Amount: 10},
+		{Delegator: "tz1b", Amount: 2000},
+	})
+
+	if len(all.Events()) != 2 {
+		t.Errorf("expected 2 delegations, got %d", len(all.Events()))
+	}
+	if len(large.Events()) != 1 {
+		t.Fatalf("expected 1 delegation, got %d", len(large.Events()))
+	}
+	if delegation := <-large.Events(); delegation.Delegator != "tz1b" {
+		t.Errorf("expected the delegation of tz1b, got %+v", delegation)
+	}
+
+	broker.Unsubscribe(large)
+	if _, ok := <-large.Events(); ok {
+		t.Errorf("expected the events to be closed")
+	}
+	// unsubscribing twice is harmless
+	broker.Unsubscribe(large)
+}
+
+func TestPublishDropsSlowSubscriber(t *testing.T) {
+	broker := NewBroker()
+	slow := broker.Subscribe(Filter{})
+
+	delegations := make([]types.Delegation, subscriptionBuffer+1)
+	broker.Publish(delegations)
+
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	if received != subscriptionBuffer {
+		t.Errorf("expected %d delegations before the subscriber is dropped, got %d", subscriptionBuffer, received)
+	}
+}
+
+func TestClose(t *testing.T) {
+	broker := NewBroker()
+	subscription := broker.Subscribe(Filter{})
+	broker.Close()
+
+	if _, ok := <-subscription.Events(); ok {
+		t.Errorf("expected the events to be closed")
+	}
+	if _, ok := <-broker.Subscribe(Filter{}).Events(); ok {
+		t.Errorf("expected the subscription of a closed broker to be closed")
+	}
+	broker.Publish([]types.Delegation{{Delegator: "tz1a"}})
+}
@@ -2,7 +2,10 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"slices"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,10 +15,71 @@ type Config struct {
 		Host        string `yaml:"host"`
 		Port        int    `yaml:"port"`
 		MetricsPort int    `yaml:"metricsPort"`
+		// port of the gRPC api, it is not served when 0
+		GrpcPort int `yaml:"grpcPort"`
 	} `yaml:"server"`
-	Tzkt struct {
+	// Source is where delegations are ingested from, SourceTzkt (default) or SourceNode
+	Source string `yaml:"source"`
+	Tzkt   struct {
 		Url string `yaml:"url"`
+		// tzkt endpoints by order of preference, the watcher fails over to the next one when a request fails.
+		// Url, when set, is the first endpoint.
+		Urls []string `yaml:"urls"`
+		// verify fetches the delegations of every new block from a second endpoint and reports the differences
+		Verify bool `yaml:"verify"`
 	} `yaml:"tzkt"`
+	Node struct {
+		// url of the RPC of an octez node, an archive node is needed to backfill old blocks
+		Url string `yaml:"url"`
+		// seconds between two polls of the head of the node
+		PollInterval int `yaml:"pollInterval"`
+	} `yaml:"node"`
+	Http struct {
+		// timeout of a request to the source
+		Timeout time.Duration `yaml:"timeout"`
+		// maximum size in bytes of a response body
+		MaxResponseSize int64 `yaml:"maxResponseSize"`
+		// requests per second sent to the source by all the calls together, 0 means no limit
+		RateLimit float64 `yaml:"rateLimit"`
+		// requests that can be sent at once after an idle period
+		Burst          int `yaml:"burst"`
+		CircuitBreaker struct {
+			// consecutive failed requests to a host opening its circuit, 0 disables the circuit breaker
+			Failures int `yaml:"failures"`
+			// time during which no request is sent to a host whose circuit is open
+			Cooldown time.Duration `yaml:"cooldown"`
+		} `yaml:"circuitBreaker"`
+		Retry struct {
+			// attempts of a request, 1 means no retry
+			MaxAttempts    int           `yaml:"maxAttempts"`
+			InitialBackoff time.Duration `yaml:"initialBackoff"`
+			MaxBackoff     time.Duration `yaml:"maxBackoff"`
+			// response status codes that are retried
+			StatusCodes []int `yaml:"statusCodes"`
+			// retry the requests that got no response, timeouts included
+			NetworkErrors bool `yaml:"networkErrors"`
+		} `yaml:"retry"`
+	} `yaml:"http"`
+	Backfill struct {
+		// number of concurrent backfill workers, the backfill is sequential when it is 1 or less
+		Workers int `yaml:"workers"`
+		// number of blocks fetched by a worker at once
+		ChunkSize int32 `yaml:"chunkSize"`
+	} `yaml:"backfill"`
+	Admin struct {
+		// bearer token of the admin endpoints, they are disabled when it is empty
+		Token string `yaml:"token"`
+	} `yaml:"admin"`
+	Webhooks struct {
+		// number of concurrent deliveries
+		Workers int `yaml:"workers"`
+		// timeout of a delivery attempt
+		Timeout time.Duration `yaml:"timeout"`
+		// attempts of a delivery before its payload is stored as a dead letter
+		MaxAttempts    int           `yaml:"maxAttempts"`
+		InitialBackoff time.Duration `yaml:"initialBackoff"`
+		MaxBackoff     time.Duration `yaml:"maxBackoff"`
+	} `yaml:"webhooks"`
 	Db struct {
 		Host     string `yaml:"host"`
 		Port     int    `yaml:"port"`
@@ -25,6 +89,41 @@ type Config struct {
 	} `yaml:"db"`
 }
 
+const DefaultBackfillChunkSize = 100000
+
+const DefaultNodePollInterval = 2
+
+const DefaultHttpTimeout = 4 * time.Second
+
+const DefaultCircuitBreakerCooldown = 30 * time.Second
+
+// DefaultMaxResponseSize leaves room for pages of 10000 delegations
+const DefaultMaxResponseSize = 64 << 20
+
+// default retry policy, applied when the config has no retry section
+const (
+	DefaultRetryMaxAttempts    = 5
+	DefaultRetryInitialBackoff = 500 * time.Millisecond
+	DefaultRetryMaxBackoff     = 30 * time.Second
+)
+
+var DefaultRetryStatusCodes = []int{429, 500, 502, 503, 504}
+
+// default webhooks delivery settings
+const (
+	DefaultWebhooksWorkers        = 4
+	DefaultWebhooksTimeout        = 5 * time.Second
+	DefaultWebhooksMaxAttempts    = 5
+	DefaultWebhooksInitialBackoff = time.Second
+	DefaultWebhooksMaxBackoff     = time.Minute
+)
+
+// ingestion sources
+const (
+	SourceTzkt = "tzkt"
+	SourceNode = "node"
+)
+
 // var config Config
 
 func LoadConfig(configPath string) (Config, error) {
@@ -46,8 +145,28 @@ func LoadConfig(configPath string) (Config, error) {
 		return Config{}, errors.New("server metrics port is required")
 	}
 
-	if cfg.Tzkt.Url == "" {
-		return Config{}, errors.New("tzkt url is required")
+	switch cfg.Source {
+	case "", SourceTzkt:
+		cfg.Source = SourceTzkt
+		if cfg.Tzkt.Url != "" && !slices.Contains(cfg.Tzkt.Urls, cfg.Tzkt.Url) {
+			cfg.Tzkt.Urls = append([]string{cfg.Tzkt.Url}, cfg.Tzkt.Urls...)
+		}
+		if len(cfg.Tzkt.Urls) == 0 {
+			return Config{}, errors.New("tzkt url is required")
+		}
+		cfg.Tzkt.Url = cfg.Tzkt.Urls[0]
+		if cfg.Tzkt.Verify && len(cfg.Tzkt.Urls) < 2 {
+			return Config{}, errors.New("tzkt verification requires at least two urls")
+		}
+	case SourceNode:
+		if cfg.Node.Url == "" {
+			return Config{}, errors.New("node url is required")
+		}
+		if cfg.Node.PollInterval == 0 {
+			cfg.Node.PollInterval = DefaultNodePollInterval
+		}
+	default:
+		return Config{}, fmt.Errorf("unknown source %q", cfg.Source)
 	}
 
 	if cfg.Db.Host == "" {
@@ -70,6 +189,56 @@ func LoadConfig(configPath string) (Config, error) {
 		return Config{}, errors.New("db database is required")
 	}
 
+	if cfg.Http.Timeout == 0 {
+		cfg.Http.Timeout = DefaultHttpTimeout
+	}
+
+	if cfg.Http.MaxResponseSize == 0 {
+		cfg.Http.MaxResponseSize = DefaultMaxResponseSize
+	}
+
+	if cfg.Http.CircuitBreaker.Failures > 0 && cfg.Http.CircuitBreaker.Cooldown == 0 {
+		cfg.Http.CircuitBreaker.Cooldown = DefaultCircuitBreakerCooldown
+	}
+
+	if cfg.Http.Retry.MaxAttempts == 0 {
+		cfg.Http.Retry.MaxAttempts = DefaultRetryMaxAttempts
+		cfg.Http.Retry.NetworkErrors = true
+		if cfg.Http.Retry.StatusCodes == nil {
+			cfg.Http.Retry.StatusCodes = DefaultRetryStatusCodes
+		}
+	}
+	if cfg.Http.Retry.InitialBackoff == 0 {
+		cfg.Http.Retry.InitialBackoff = DefaultRetryInitialBackoff
+	}
+	if cfg.Http.Retry.MaxBackoff == 0 {
+		cfg.Http.Retry.MaxBackoff = DefaultRetryMaxBackoff
+	}
+
+	if cfg.Backfill.ChunkSize == 0 {
+		cfg.Backfill.ChunkSize = DefaultBackfillChunkSize
+	}
+
+	if cfg.Backfill.ChunkSize < 0 {
+		return Config{}, errors.New("backfill chunk size must be positive")
+	}
+
+	if cfg.Webhooks.Workers == 0 {
+		cfg.Webhooks.Workers = DefaultWebhooksWorkers
+	}
+	if cfg.Webhooks.Timeout == 0 {
+		cfg.Webhooks.Timeout = DefaultWebhooksTimeout
+	}
+	if cfg.Webhooks.MaxAttempts == 0 {
+		cfg.Webhooks.MaxAttempts = DefaultWebhooksMaxAttempts
+	}
+	if cfg.Webhooks.InitialBackoff == 0 {
+		cfg.Webhooks.InitialBackoff = DefaultWebhooksInitialBackoff
+	}
+	if cfg.Webhooks.MaxBackoff == 0 {
+		cfg.Webhooks.MaxBackoff = DefaultWebhooksMaxBackoff
+	}
+
 	// config = cfg
 	return cfg, nil
 }
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func writeTempConfig(t *testing.T, content string) string {
@@ -25,6 +26,7 @@ server:
   host: "localhost"
   port: 8080
   metricsPort: 9090
+  grpcPort: 9091
 tzkt:
   url: "http://tzkt.io"
 db:
@@ -40,7 +42,7 @@ db:
 	if err != nil {
 		t.Fatalf("expected success, got error: %v", err)
 	}
-	if cfg.Server.Host != "localhost" || cfg.Server.Port != 8080 || cfg.Server.MetricsPort != 9090 {
+	if cfg.Server.Host != "localhost" || cfg.Server.Port != 8080 || cfg.Server.MetricsPort != 9090 || cfg.Server.GrpcPort != 9091 {
 		t.Errorf("server config not loaded correctly: %+v", cfg.Server)
 	}
 	if cfg.Tzkt.Url != "http://tzkt.io" {
@@ -51,6 +53,33 @@ db:
 	}
 }
 
+func TestLoadConfig_Backfill(t *testing.T) {
+	configYAML := `
+server:
+  port: 8080
+  metricsPort: 9090
+tzkt:
+  url: "http://tzkt.io"
+db:
+  host: "dbhost"
+  port: 5432
+  user: "user"
+  password: "pass"
+  database: "mydb"
+backfill:
+  workers: 4
+`
+	path := writeTempConfig(t, configYAML)
+	defer os.Remove(path)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if cfg.Backfill.Workers != 4 || cfg.Backfill.ChunkSize != DefaultBackfillChunkSize {
+		t.Errorf("backfill config not loaded correctly: %+v", cfg.Backfill)
+	}
+}
+
 func TestLoadConfig_MissingFields(t *testing.T) {
 	cases := []struct {
 		name    string
@@ -203,6 +232,169 @@ db:
 	}
 }
 
+func TestLoadConfig_TzktUrls(t *testing.T) {
+	configYAML := `
+server:
+  port: 8080
+  metricsPort: 9090
+tzkt:
+  url: "http://tzkt.local"
+  urls:
+    - "https://api.tzkt.io"
+  verify: true
+db:
+  host: "dbhost"
+  port: 5432
+  user: "user"
+  password: "pass"
+  database: "mydb"
+`
+	path := writeTempConfig(t, configYAML)
+	defer os.Remove(path)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if len(cfg.Tzkt.Urls) != 2 || cfg.Tzkt.Urls[0] != "http://tzkt.local" || cfg.Tzkt.Urls[1] != "https://api.tzkt.io" || cfg.Tzkt.Url != "http://tzkt.local" {
+		t.Errorf("tzkt urls not loaded correctly: %+v", cfg.Tzkt)
+	}
+
+	// verification needs a second endpoint
+	path = writeTempConfig(t, strings.Replace(configYAML, `url: "http://tzkt.local"`, "", 1))
+	defer os.Remove(path)
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "at least two urls") {
+		t.Errorf("expected verification error, got %v", err)
+	}
+}
+
+func TestLoadConfig_Http(t *testing.T) {
+	configYAML := `
+server:
+  port: 8080
+  metricsPort: 9090
+tzkt:
+  url: "http://tzkt.io"
+db:
+  host: "dbhost"
+  port: 5432
+  user: "user"
+  password: "pass"
+  database: "mydb"
+`
+	path := writeTempConfig(t, configYAML)
+	defer os.Remove(path)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	retry := cfg.Http.Retry
+	if cfg.Http.Timeout != DefaultHttpTimeout || cfg.Http.MaxResponseSize != DefaultMaxResponseSize || retry.MaxAttempts != DefaultRetryMaxAttempts || !retry.NetworkErrors || len(retry.StatusCodes) != len(DefaultRetryStatusCodes) {
+		t.Errorf("http defaults not applied: %+v", cfg.Http)
+	}
+
+	path = writeTempConfig(t, configYAML+`
+http:
+  timeout: 10s
+  retry:
+    maxAttempts: 3
+    initialBackoff: 1s
+    statusCodes: [503]
+  rateLimit: 10
+  burst: 4
+  circuitBreaker:
+    failures: 5
+`)
+	defer os.Remove(path)
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if cfg.Http.RateLimit != 10 || cfg.Http.Burst != 4 || cfg.Http.CircuitBreaker.Failures != 5 || cfg.Http.CircuitBreaker.Cooldown != DefaultCircuitBreakerCooldown {
+		t.Errorf("rate limit and circuit breaker not loaded correctly: %+v", cfg.Http)
+	}
+	retry = cfg.Http.Retry
+	if cfg.Http.Timeout != 10*time.Second || retry.MaxAttempts != 3 || retry.InitialBackoff != time.Second || retry.MaxBackoff != DefaultRetryMaxBackoff {
+		t.Errorf("http config not loaded correctly: %+v", cfg.Http)
+	}
+	if retry.NetworkErrors || len(retry.StatusCodes) != 1 || retry.StatusCodes[0] != 503 {
+		t.Errorf("retry policy not loaded correctly: %+v", retry)
+	}
+}
+
+func TestLoadConfig_Webhooks(t *testing.T) {
+	configYAML := `
+server:
+  port: 8080
+  metricsPort: 9090
+tzkt:
+  url: "http://tzkt.io"
+db:
+  host: "dbhost"
+  port: 5432
+  user: "user"
+  password: "pass"
+  database: "mydb"
+admin:
+  token: "secret"
+webhooks:
+  maxAttempts: 3
+  timeout: 1s
+`
+	path := writeTempConfig(t, configYAML)
+	defer os.Remove(path)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if cfg.Admin.Token != "secret" {
+		t.Errorf("expected admin token to be loaded, got %q", cfg.Admin.Token)
+	}
+	webhooks := cfg.Webhooks
+	if webhooks.MaxAttempts != 3 || webhooks.Timeout != time.Second {
+		t.Errorf("webhooks config not loaded correctly: %+v", webhooks)
+	}
+	if webhooks.Workers != DefaultWebhooksWorkers || webhooks.InitialBackoff != DefaultWebhooksInitialBackoff || webhooks.MaxBackoff != DefaultWebhooksMaxBackoff {
+		t.Errorf("webhooks defaults not applied: %+v", webhooks)
+	}
+}
+
+func TestLoadConfig_NodeSource(t *testing.T) {
+	configYAML := `
+server:
+  port: 8080
+  metricsPort: 9090
+source: node
+node:
+  url: "http://localhost:8732"
+db:
+  host: "dbhost"
+  port: 5432
+  user: "user"
+  password: "pass"
+  database: "mydb"
+`
+	path := writeTempConfig(t, configYAML)
+	defer os.Remove(path)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if cfg.Source != SourceNode || cfg.Node.Url != "http://localhost:8732" || cfg.Node.PollInterval != DefaultNodePollInterval {
+		t.Errorf("node config not loaded correctly: %v %+v", cfg.Source, cfg.Node)
+	}
+
+	for _, content := range []string{
+		strings.Replace(configYAML, `url: "http://localhost:8732"`, "", 1),
+		strings.Replace(configYAML, "source: node", "source: other", 1),
+	} {
+		path := writeTempConfig(t, content)
+		defer os.Remove(path)
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("expected error for config %s", content)
+		}
+	}
+}
+
 func TestLoadConfig_InvalidYAML(t *testing.T) {
 	badYAML := `server: [bad yaml`
 	path := writeTempConfig(t, badYAML)
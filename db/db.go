@@ -1,30 +1,67 @@
 package db
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/ibraheemacara/tezos-delegation-service/config"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// ErrNotFound is returned when the record to read, update or delete does not exist
+var ErrNotFound = errors.New("record not found")
+
 type DbStore struct {
 	DB      *gorm.DB
-	pgxConn *pgx.Conn
+	pgxPool *pgxpool.Pool
+}
+
+// Cursor points at the last delegation of a page, the next page starts right after it
+type Cursor struct {
+	Block int32
+	ID    uint
 }
 
 type DBInterface interface {
-	GetDelegations() ([]Delegations, error)
-	GetDelegationsByYear(year string) ([]Delegations, error)
-	GetLastBlock() (int32, error)
-	InsertDelegations(delegator string, timestamp time.Time, block int32, amount int64) error
-	BulkInsertDelegations(delegations []Delegations) error
+	GetDelegations(ctx context.Context, filter DelegationsFilter, cursor *Cursor, limit int) ([]Delegations, error)
+	ExportDelegations(ctx context.Context, filter DelegationsFilter, write func([]Delegations) error) error
+	GetDelegationsByDelegator(ctx context.Context, delegator string, cursor *Cursor, limit int) ([]Delegations, error)
+	GetDelegationsByBaker(ctx context.Context, baker string, cursor *Cursor, limit int) ([]Delegations, error)
+	GetSyncLevel(ctx context.Context, stream string) (int32, error)
+	GetLastBlock(ctx context.Context) (int32, error)
+	UpdateSyncLevel(ctx context.Context, stream string, level int32) error
+	InsertDelegations(ctx context.Context, delegation Delegations) error
+	BulkInsertDelegations(ctx context.Context, delegations []Delegations, checkpoint *SyncState) ([]Delegations, error)
+	DeleteDelegationsAboveBlock(ctx context.Context, block int32) (int64, error)
+	GetBackfillChunks(ctx context.Context, afterLevel int32) ([]BackfillChunk, error)
+	CreateBackfillChunks(ctx context.Context, chunks []BackfillChunk) error
+	CompleteBackfillChunk(ctx context.Context, fromLevel int32) error
+	GetDelegatorHistory(ctx context.Context, delegator string) ([]Delegations, error)
+	GetBakerDelegators(ctx context.Context, baker string, cursor *Cursor, limit int) ([]Delegations, error)
+	GetBakerTotals(ctx context.Context, baker string) (BakerTotals, error)
+	GetDelegationStats(ctx context.Context, query StatsQuery) ([]DelegationStats, error)
+	GetLeaderboard(ctx context.Context, query LeaderboardQuery) ([]LeaderboardEntry, error)
+	RefreshLeaderboards(ctx context.Context) error
+	GetWebhooks(ctx context.Context) ([]Webhook, error)
+	GetWebhook(ctx context.Context, id uint) (Webhook, error)
+	CreateWebhook(ctx context.Context, webhook *Webhook) error
+	UpdateWebhook(ctx context.Context, webhook *Webhook) error
+	DeleteWebhook(ctx context.Context, id uint) error
+	InsertWebhookDeadLetter(ctx context.Context, deadLetter WebhookDeadLetter) error
+	GetWebhookDeadLetters(ctx context.Context, webhookID uint) ([]WebhookDeadLetter, error)
+	Close() error
 }
 
 func InitDB(cfg config.Config) (DBInterface, error) {
@@ -35,86 +72,466 @@ func InitDB(cfg config.Config) (DBInterface, error) {
 		return nil, err
 	}
 
-	pgxConn, err := pgx.Connect(context.Background(), connectionString)
+	// the backfill and the blocks watcher insert concurrently, a single pgx.Conn can't be shared between them
+	pgxPool, err := pgxpool.New(context.Background(), connectionString)
 	if err != nil {
 		return nil, err
 	}
 
 	dbStore := &DbStore{
 		DB:      gormDB,
-		pgxConn: pgxConn,
+		pgxPool: pgxPool,
+	}
+
+	if err := dbStore.checkLegacyDelegations(); err != nil {
+		return nil, err
+	}
+	if err := dbStore.DB.AutoMigrate(&Delegations{}, &SyncState{}, &BackfillChunk{}, &Webhook{}, &WebhookDeadLetter{}); err != nil {
+		return nil, err
 	}
 
-	if err := dbStore.DB.AutoMigrate(&Delegations{}); err != nil {
+	if err := dbStore.claimSource(context.Background(), cfg.Source); err != nil {
 		return nil, err
 	}
 
+	for _, query := range createLeaderboardViewQueries {
+		if err := dbStore.DB.Exec(query).Error; err != nil {
+			return nil, err
+		}
+	}
+
 	log.Info("Database initialized successfully")
 
 	return dbStore, nil
 }
 
-func (db *DbStore) GetDelegations() ([]Delegations, error) {
+// checkLegacyDelegations refuses the delegations stored before their operation id was recorded. They have no hash
+// nor delegates, and the operation ids skipping the delegations already stored can't be set on them.
+func (db *DbStore) checkLegacyDelegations() error {
+	migrator := db.DB.Migrator()
+	if !migrator.HasTable(&Delegations{}) {
+		return nil
+	}
+	query := db.DB.Model(&Delegations{})
+	if migrator.HasColumn(&Delegations{}, "OperationID") {
+		query = query.Where("operation_id IS NULL")
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%d delegations were stored without their operation id, empty the database to ingest them again (see Upgrading in the README)", count)
+	}
+	return nil
+}
+
+// claimSource records the ingestion source of the database on its streams. The ids of the delegations read from a
+// node differ from the tzkt operation ids, a database filled from a source is not ingested from the other one:
+// the same delegations would be stored twice.
+func (db *DbStore) claimSource(ctx context.Context, source string) error {
+	tx, err := db.pgxPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, claimSourceQuery, SyncStreamBackfill, SyncStreamLive, source); err != nil {
+		return err
+	}
+	var stored string
+	err = tx.QueryRow(ctx, "SELECT source FROM sync_state WHERE source <> $1 LIMIT 1", source).Scan(&stored)
+	if err == nil {
+		return fmt.Errorf("the database was filled from source %s, it can't be ingested from %s", stored, source)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// claimSourceQuery creates the streams with the source, or sets it on the streams recorded without one
+const claimSourceQuery = `INSERT INTO sync_state (stream, level, source, updated_at) VALUES ($1, 0, $3, now()), ($2, 0, $3, now())
+ON CONFLICT (stream) DO UPDATE SET source = EXCLUDED.source WHERE sync_state.source = ''`
+
+// GetDelegations returns the delegations selected by the filter
+func (db *DbStore) GetDelegations(ctx context.Context, filter DelegationsFilter, cursor *Cursor, limit int) ([]Delegations, error) {
 	var delegations []Delegations
-	if err := db.DB.Order("block DESC").Limit(50).Find(&delegations).Error; err != nil {
+	query := db.DB.WithContext(ctx).Scopes(filter.scope)
+	if err := paginate(query, cursor, limit).Find(&delegations).Error; err != nil {
 		return nil, err
 	}
 	return delegations, nil
 }
 
-func (db *DbStore) GetDelegationsByYear(year string) ([]Delegations, error) {
+func (db *DbStore) GetDelegationsByDelegator(ctx context.Context, delegator string, cursor *Cursor, limit int) ([]Delegations, error) {
 	var delegations []Delegations
-	if err := db.DB.Where("EXTRACT(YEAR FROM timestamp) = ?", year).Order("block DESC").Limit(50).Find(&delegations).Error; err != nil {
+	query := db.DB.WithContext(ctx).Where("delegator = ?", delegator)
+	if err := paginate(query, cursor, limit).Find(&delegations).Error; err != nil {
 		return nil, err
 	}
 	return delegations, nil
 }
 
-func (db *DbStore) InsertDelegations(delegator string, timestamp time.Time, block int32, amount int64) error {
-	delegation := Delegations{
-		Delegator: delegator,
-		Timestamp: timestamp,
-		Block:     block,
-		Amount:    amount,
+func (db *DbStore) GetDelegationsByBaker(ctx context.Context, baker string, cursor *Cursor, limit int) ([]Delegations, error) {
+	var delegations []Delegations
+	query := db.DB.WithContext(ctx).Where("new_delegate = ?", baker)
+	if err := paginate(query, cursor, limit).Find(&delegations).Error; err != nil {
+		return nil, err
 	}
-	return db.DB.Create(&delegation).Error
+	return delegations, nil
 }
 
-func (db *DbStore) GetLastBlock() (int32, error) {
-	var delegation Delegations
-	err := db.DB.Order("block DESC").Limit(1).Find(&delegation).Error
-	if err == gorm.ErrRecordNotFound {
-		return 0, nil
+// GetDelegatorHistory returns all the delegations of the delegator from the oldest to the newest
+func (db *DbStore) GetDelegatorHistory(ctx context.Context, delegator string) ([]Delegations, error) {
+	var delegations []Delegations
+	if err := db.DB.WithContext(ctx).Where("delegator = ?", delegator).Order("block ASC, id ASC").Find(&delegations).Error; err != nil {
+		return nil, err
 	}
-	if err != nil {
+	return delegations, nil
+}
+
+// GetBakerDelegators returns the delegators whose newest delegation is to the baker, each with the delegation that
+// started its delegation to the baker. Delegators are ordered by that delegation from the newest to the oldest.
+func (db *DbStore) GetBakerDelegators(ctx context.Context, baker string, cursor *Cursor, limit int) ([]Delegations, error) {
+	var delegations []Delegations
+	if err := paginate(currentDelegators(db.DB.WithContext(ctx), baker), cursor, limit).Find(&delegations).Error; err != nil {
+		return nil, err
+	}
+	return delegations, nil
+}
+
+// GetBakerTotals counts the current delegators of the baker and sums their balance when they started delegating to it
+func (db *DbStore) GetBakerTotals(ctx context.Context, baker string) (BakerTotals, error) {
+	var totals BakerTotals
+	err := currentDelegators(db.DB.WithContext(ctx), baker).
+		Select("COUNT(*) AS delegators, COALESCE(SUM(amount), 0)::bigint AS total_amount").
+		Scan(&totals).Error
+	return totals, err
+}
+
+// currentDelegators selects, for each delegator currently delegating to the baker, the oldest of its delegations
+// to the baker not followed by a delegation to another delegate or a removal of the delegate
+func currentDelegators(tx *gorm.DB, baker string) *gorm.DB {
+	since := tx.Table("delegations AS d").
+		Select("DISTINCT ON (d.delegator) d.*").
+		Where("d.new_delegate = ?", baker).
+		Where("NOT EXISTS (SELECT 1 FROM delegations o WHERE o.delegator = d.delegator AND o.new_delegate <> ? AND (o.block, o.id) > (d.block, d.id))", baker).
+		Order("d.delegator, d.block ASC, d.id ASC")
+	return tx.Table("(?) AS delegations", since)
+}
+
+// paginate orders delegations from newest to oldest and keeps only the ones after the cursor
+func paginate(query *gorm.DB, cursor *Cursor, limit int) *gorm.DB {
+	if cursor != nil {
+		query = query.Where("(block, id) < (?, ?)", cursor.Block, cursor.ID)
+	}
+	return query.Order("block DESC, id DESC").Limit(limit)
+}
+
+func (db *DbStore) InsertDelegations(ctx context.Context, delegation Delegations) error {
+	return db.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "operation_id"}},
+		DoNothing: true,
+	}).Create(&delegation).Error
+}
+
+// GetSyncLevel returns the last block processed by the stream, 0 if it never ran
+func (db *DbStore) GetSyncLevel(ctx context.Context, stream string) (int32, error) {
+	var state SyncState
+	if err := db.DB.WithContext(ctx).Where("stream = ?", stream).Limit(1).Find(&state).Error; err != nil {
+		return 0, err
+	}
+	return state.Level, nil
+}
+
+// GetLastBlock returns the highest block of the stored delegations, 0 if none is stored
+func (db *DbStore) GetLastBlock(ctx context.Context) (int32, error) {
+	var level int32
+	if err := db.DB.WithContext(ctx).Model(&Delegations{}).Select("COALESCE(MAX(block), 0)").Scan(&level).Error; err != nil {
 		return 0, err
 	}
-	return delegation.Block, nil
+	return level, nil
 }
 
-func (db *DbStore) BulkInsertDelegations(delegations []Delegations) error {
+// UpdateSyncLevel records level as processed by the stream, the level of a stream never goes back
+func (db *DbStore) UpdateSyncLevel(ctx context.Context, stream string, level int32) error {
+	_, err := db.pgxPool.Exec(ctx, upsertSyncStateQuery, stream, level)
+	return err
+}
+
+// upsertSyncStateQuery only moves a stream forward, rollbacks are done by DeleteDelegationsAboveBlock
+const upsertSyncStateQuery = `INSERT INTO sync_state (stream, level, updated_at) VALUES ($1, $2, now())
+ON CONFLICT (stream) DO UPDATE SET level = GREATEST(sync_state.level, EXCLUDED.level), updated_at = now()`
+
+// delegationColumns are the columns written by BulkInsertDelegations
+var delegationColumns = []string{"operation_id", "hash", "delegator", "timestamp", "block", "amount", "new_delegate", "prev_delegate"}
 
-	ctx := context.Background()
-	// defer db.pgxConn.Close(ctx)
+// BulkInsertDelegations copies delegations into a staging table and moves them into the delegations table,
+// delegations that are already stored are skipped. The checkpoint, if any, is saved in the same transaction.
+// It returns the delegations actually inserted, ordered by block and operation id.
+func (db *DbStore) BulkInsertDelegations(ctx context.Context, delegations []Delegations, checkpoint *SyncState) ([]Delegations, error) {
+	tx, err := db.pgxPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
 
-	copyCount, err := db.pgxConn.CopyFrom(
+	columns := strings.Join(delegationColumns, ", ")
+	_, err = tx.Exec(ctx, fmt.Sprintf("CREATE TEMP TABLE delegations_staging ON COMMIT DROP AS SELECT %s FROM delegations WITH NO DATA", columns))
+	if err != nil {
+		return nil, err
+	}
+
+	copyCount, err := tx.CopyFrom(
 		ctx,
-		pgx.Identifier{"delegations"},
-		[]string{"delegator", "timestamp", "block", "amount"},
+		pgx.Identifier{"delegations_staging"},
+		delegationColumns,
 		pgx.CopyFromSlice(len(delegations), func(i int) ([]interface{}, error) {
 			delegation := delegations[i]
 			return []interface{}{
+				delegation.OperationID,
+				delegation.Hash,
 				delegation.Delegator,
 				delegation.Timestamp,
 				delegation.Block,
 				delegation.Amount,
+				delegation.NewDelegate,
+				delegation.PrevDelegate,
 			}, nil
 		}),
 	)
+	if err != nil {
+		return nil, err
+	}
 
+	rows, err := tx.Query(ctx, fmt.Sprintf("INSERT INTO delegations (%s) SELECT %s FROM delegations_staging ON CONFLICT (operation_id) DO NOTHING RETURNING id, %s", columns, columns, columns))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	inserted, err := pgx.CollectRows(rows, scanInsertedDelegation)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkpoint != nil {
+		if _, err := tx.Exec(ctx, upsertSyncStateQuery, checkpoint.Stream, checkpoint.Level); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	log.Infof("Copied %d delegations to database, %d already stored", len(inserted), copyCount-int64(len(inserted)))
+	// RETURNING does not keep the order of the staging table
+	slices.SortFunc(inserted, func(a, b Delegations) int {
+		return cmp.Or(cmp.Compare(a.Block, b.Block), cmp.Compare(a.OperationID, b.OperationID))
+	})
+	return inserted, nil
+}
+
+// scanInsertedDelegation reads a row returned by the insert of BulkInsertDelegations, the id then delegationColumns
+func scanInsertedDelegation(row pgx.CollectableRow) (Delegations, error) {
+	var delegation Delegations
+	err := row.Scan(&delegation.ID, &delegation.OperationID, &delegation.Hash, &delegation.Delegator, &delegation.Timestamp,
+		&delegation.Block, &delegation.Amount, &delegation.NewDelegate, &delegation.PrevDelegate)
+	return delegation, err
+}
+
+// DeleteDelegationsAboveBlock removes the delegations of the blocks reverted by a chain reorganization,
+// moves the streams that processed them back to block and marks the backfill chunks holding them as not done
+func (db *DbStore) DeleteDelegationsAboveBlock(ctx context.Context, block int32) (int64, error) {
+	var deleted int64
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("block > ?", block).Delete(&Delegations{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		if err := tx.Model(&SyncState{}).Where("level > ?", block).Updates(map[string]any{"level": block, "updated_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		return resetBackfillChunks(tx, block).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// resetBackfillChunks marks the chunks ending after block as not done, an interrupted backfill fetches them again
+func resetBackfillChunks(tx *gorm.DB, block int32) *gorm.DB {
+	return tx.Model(&BackfillChunk{}).Where("to_level > ? AND done", block).Updates(map[string]any{"done": false, "updated_at": time.Now()})
+}
+
+// GetBackfillChunks returns the chunks of the parallel backfill ending after afterLevel, ordered by level
+func (db *DbStore) GetBackfillChunks(ctx context.Context, afterLevel int32) ([]BackfillChunk, error) {
+	var chunks []BackfillChunk
+	if err := db.DB.WithContext(ctx).Where("to_level > ?", afterLevel).Order("from_level ASC").Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func (db *DbStore) CreateBackfillChunks(ctx context.Context, chunks []BackfillChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	return db.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&chunks).Error
+}
+
+func (db *DbStore) CompleteBackfillChunk(ctx context.Context, fromLevel int32) error {
+	return db.DB.WithContext(ctx).Model(&BackfillChunk{}).Where("from_level = ?", fromLevel).Updates(map[string]any{"done": true, "updated_at": time.Now()}).Error
+}
+
+// GetDelegationStats aggregates the delegations by period, and by baker if asked, from the oldest period to the newest
+func (db *DbStore) GetDelegationStats(ctx context.Context, query StatsQuery) ([]DelegationStats, error) {
+	// periods are computed in UTC whatever the time zone of the session
+	period := "date_trunc(?, timestamp AT TIME ZONE 'UTC')"
+	columns := period + ` AS period, COUNT(*) AS count, SUM(amount)::bigint AS total_amount,
+AVG(amount)::float8 AS average_amount, COUNT(DISTINCT delegator) AS unique_delegators`
+	groupBy := "period"
+	if query.ByBaker {
+		columns += ", new_delegate AS baker"
+		groupBy += ", baker"
+	}
+
+	tx := db.DB.WithContext(ctx).Model(&Delegations{}).Select(columns, query.Interval)
+	if !query.From.IsZero() {
+		tx = tx.Where("timestamp >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		tx = tx.Where("timestamp < ?", query.To)
+	}
+
+	stats := []DelegationStats{}
+	if err := tx.Group(groupBy).Order(groupBy).Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// leaderboardView sums the delegations by day and by delegator or baker, the leaderboards of any range of days are computed from it
+const leaderboardView = "delegation_daily_totals"
+
+var createLeaderboardViewQueries = []string{
+	`CREATE MATERIALIZED VIEW IF NOT EXISTS ` + leaderboardView + ` AS
+SELECT 'delegator' AS role, delegator AS address, (timestamp AT TIME ZONE 'UTC')::date AS day, COUNT(*) AS count, SUM(amount)::bigint AS total_amount
+FROM delegations GROUP BY 2, 3
+UNION ALL
+SELECT 'baker', new_delegate, (timestamp AT TIME ZONE 'UTC')::date, COUNT(*), SUM(amount)::bigint
+FROM delegations WHERE new_delegate <> '' GROUP BY 2, 3`,
+	// the unique index is required by the concurrent refreshes, which don't block the reads of the leaderboards
+	`CREATE UNIQUE INDEX IF NOT EXISTS ` + leaderboardView + `_key ON ` + leaderboardView + ` (role, address, day)`,
+	`CREATE INDEX IF NOT EXISTS ` + leaderboardView + `_day ON ` + leaderboardView + ` (role, day)`,
+}
+
+// GetLeaderboard ranks the addresses from the highest value to the lowest, ties are ordered by address
+func (db *DbStore) GetLeaderboard(ctx context.Context, query LeaderboardQuery) ([]LeaderboardEntry, error) {
+	value := "SUM(total_amount)"
+	if query.OrderBy == LeaderboardByCount {
+		value = "SUM(count)"
+	}
+
+	tx := db.DB.WithContext(ctx).Table(leaderboardView).
+		Select("address, SUM(count)::bigint AS count, SUM(total_amount)::bigint AS total_amount").
+		Where("role = ?", query.Role)
+	if query.Year != 0 {
+		tx = tx.Where("day >= make_date(?, 1, 1) AND day < make_date(?, 1, 1)", query.Year, query.Year+1)
+	}
+	// the days are compared as dates, a time would be converted with the time zone of the session
+	if !query.From.IsZero() {
+		tx = tx.Where("day >= ?::date", query.From.Format(time.DateOnly))
+	}
+	if !query.To.IsZero() {
+		tx = tx.Where("day < ?::date", query.To.Format(time.DateOnly))
+	}
+	tx = tx.Group("address")
+	if query.Cursor != nil {
+		tx = tx.Having(fmt.Sprintf("%s < ? OR (%s = ? AND address > ?)", value, value), query.Cursor.Value, query.Cursor.Value, query.Cursor.Address)
+	}
+
+	entries := []LeaderboardEntry{}
+	if err := tx.Order(value + " DESC, address ASC").Limit(query.Limit).Scan(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// RefreshLeaderboards recomputes the leaderboards from the stored delegations
+func (db *DbStore) RefreshLeaderboards(ctx context.Context) error {
+	_, err := db.pgxPool.Exec(ctx, "REFRESH MATERIALIZED VIEW CONCURRENTLY "+leaderboardView)
+	return err
+}
+
+// GetWebhooks returns all the webhooks ordered by id
+func (db *DbStore) GetWebhooks(ctx context.Context) ([]Webhook, error) {
+	var webhooks []Webhook
+	if err := db.DB.WithContext(ctx).Order("id ASC").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (db *DbStore) GetWebhook(ctx context.Context, id uint) (Webhook, error) {
+	var webhook Webhook
+	err := db.DB.WithContext(ctx).First(&webhook, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Webhook{}, ErrNotFound
+	}
+	return webhook, err
+}
+
+func (db *DbStore) CreateWebhook(ctx context.Context, webhook *Webhook) error {
+	return db.DB.WithContext(ctx).Create(webhook).Error
+}
+
+// UpdateWebhook saves all the fields of the webhook, ErrNotFound is returned when it does not exist
+func (db *DbStore) UpdateWebhook(ctx context.Context, webhook *Webhook) error {
+	result := db.DB.WithContext(ctx).Model(webhook).Select("*").Omit("id", "created_at").Updates(webhook)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
 	}
-	log.Infof("Copied %d delegations to database", copyCount)
 	return nil
 }
+
+// DeleteWebhook removes the webhook and its dead letters
+func (db *DbStore) DeleteWebhook(ctx context.Context, id uint) error {
+	return db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&Webhook{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Where("webhook_id = ?", id).Delete(&WebhookDeadLetter{}).Error
+	})
+}
+
+func (db *DbStore) InsertWebhookDeadLetter(ctx context.Context, deadLetter WebhookDeadLetter) error {
+	return db.DB.WithContext(ctx).Create(&deadLetter).Error
+}
+
+// GetWebhookDeadLetters returns the dead letters of the webhook from the newest to the oldest
+func (db *DbStore) GetWebhookDeadLetters(ctx context.Context, webhookID uint) ([]WebhookDeadLetter, error) {
+	var deadLetters []WebhookDeadLetter
+	if err := db.DB.WithContext(ctx).Where("webhook_id = ?", webhookID).Order("id DESC").Find(&deadLetters).Error; err != nil {
+		return nil, err
+	}
+	return deadLetters, nil
+}
+
+// Close releases the connections to the database
+func (db *DbStore) Close() error {
+	db.pgxPool.Close()
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
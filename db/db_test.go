@@ -1,8 +1,11 @@
 package db
 
 import (
+	"strings"
 	"testing"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // MockDB implements DBInterface for unit testing
@@ -47,3 +50,15 @@ func TestInsertAndGetDelegations_Mock(t *testing.T) {
 		t.Errorf("Delegations not mocked as expected: %+v", delegations)
 	}
 }
+
+func TestResetBackfillChunks(t *testing.T) {
+	// updates run in a transaction unless skipped, a dry run can't begin one
+	stmt := resetBackfillChunks(dryRunDB(t).Session(&gorm.Session{SkipDefaultTransaction: true}), 150).Statement
+	sql := stmt.SQL.String()
+	if !strings.HasPrefix(sql, `UPDATE "backfill_chunks" SET "done"=$1`) || !strings.Contains(sql, "WHERE to_level > $3 AND done") {
+		t.Errorf("expected the done chunks ending after the block to be reset, got %s", sql)
+	}
+	if stmt.Vars[0] != false || stmt.Vars[2] != int32(150) {
+		t.Errorf("expected done set to false for the chunks after block 150, got %v", stmt.Vars)
+	}
+}
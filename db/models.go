@@ -3,9 +3,142 @@ package db
 import "time"
 
 type Delegations struct {
-	ID        uint      `gorm:"primarykey" json:"-"`
-	Delegator string    `gorm:"not null"`
-	Timestamp time.Time `gorm:"ype:timestamp;not null"`
-	Block     int32     `gorm:"not null"`
-	Amount    int64     `gorm:"not null"`
+	ID uint `gorm:"primarykey" json:"-"`
+	// tzkt operation id, or the id built by the node source, unique so that a range of blocks can be ingested several times
+	OperationID int64     `gorm:"not null;uniqueIndex"`
+	Hash        string    `gorm:"not null;default:''"`
+	Delegator   string    `gorm:"not null;index"`
+	Timestamp   time.Time `gorm:"ype:timestamp;not null;index"`
+	Block       int32     `gorm:"not null"`
+	Amount      int64     `gorm:"not null"`
+	// empty when the delegator removed its delegate
+	NewDelegate string `gorm:"not null;default:'';index"`
+	// empty when the delegator had no delegate before
+	PrevDelegate string `gorm:"not null;default:''"`
+}
+
+const (
+	SyncStreamBackfill = "backfill"
+	SyncStreamLive     = "live"
+)
+
+// SyncState records the last block fully processed by an ingestion stream
+type SyncState struct {
+	Stream string `gorm:"primarykey"`
+	Level  int32  `gorm:"not null"`
+	// Source is the ingestion source that filled the database, see claimSource
+	Source    string `gorm:"not null;default:''"`
+	UpdatedAt time.Time
+}
+
+func (SyncState) TableName() string {
+	return "sync_state"
+}
+
+// BackfillChunk is a range of blocks ]FromLevel, ToLevel] fetched by a parallel backfill worker
+type BackfillChunk struct {
+	FromLevel int32 `gorm:"primarykey;autoIncrement:false"`
+	ToLevel   int32 `gorm:"not null"`
+	Done      bool  `gorm:"not null;default:false"`
+	UpdatedAt time.Time
+}
+
+// Webhook is a callback receiving the new delegations matching its filters, empty filters match every delegation
+type Webhook struct {
+	ID  uint   `gorm:"primarykey"`
+	Url string `gorm:"not null"`
+	// key of the HMAC signature of the payloads
+	Secret    string `gorm:"not null"`
+	Delegator string `gorm:"not null;default:''"`
+	Baker     string `gorm:"not null;default:''"`
+	MinAmount int64  `gorm:"not null;default:0"`
+	Active    bool   `gorm:"not null;default:true"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// WebhookDeadLetter is a payload that could not be delivered to a webhook
+type WebhookDeadLetter struct {
+	ID        uint   `gorm:"primarykey"`
+	WebhookID uint   `gorm:"not null;index"`
+	Payload   string `gorm:"type:jsonb;not null"`
+	Attempts  int    `gorm:"not null"`
+	// error of the last attempt
+	Error     string `gorm:"not null;default:''"`
+	CreatedAt time.Time
+}
+
+// stats intervals, the names of the date_trunc fields of postgres
+const (
+	StatsIntervalYear  = "year"
+	StatsIntervalMonth = "month"
+	StatsIntervalDay   = "day"
+)
+
+// StatsQuery selects the delegations aggregated by GetDelegationStats
+type StatsQuery struct {
+	// Interval is the length of the periods, one of the StatsInterval constants
+	Interval string
+	// ByBaker also groups the delegations by baker
+	ByBaker bool
+	// From and To bound the timestamps of the delegations to [From, To[, zero means no bound
+	From time.Time
+	To   time.Time
+}
+
+// DelegationStats aggregates the delegations of a period, and of a baker when grouped by baker
+type DelegationStats struct {
+	Period           time.Time
+	Baker            string
+	Count            int64
+	TotalAmount      int64
+	AverageAmount    float64
+	UniqueDelegators int64
+}
+
+// leaderboards, the role of the ranked addresses
+const (
+	LeaderboardDelegators = "delegator"
+	LeaderboardBakers     = "baker"
+)
+
+// leaderboard rankings
+const (
+	LeaderboardByAmount = "amount"
+	LeaderboardByCount  = "count"
+)
+
+// LeaderboardQuery selects a page of a leaderboard
+type LeaderboardQuery struct {
+	// Role is LeaderboardDelegators or LeaderboardBakers
+	Role string
+	// OrderBy ranks the addresses by LeaderboardByAmount or LeaderboardByCount
+	OrderBy string
+	// Year keeps the delegations of the year, 0 means all years
+	Year int
+	// From and To bound the days of the delegations to [From, To[, zero means no bound
+	From time.Time
+	To   time.Time
+	// Cursor, if any, is the last address of the previous page
+	Cursor *LeaderboardCursor
+	Limit  int
+}
+
+// LeaderboardCursor points at an address of a leaderboard and its ranked value, the next page starts right after it
+type LeaderboardCursor struct {
+	Value   int64
+	Address string
+}
+
+// LeaderboardEntry totals the delegations of a delegator, or the delegations to a baker
+type LeaderboardEntry struct {
+	Address     string
+	Count       int64
+	TotalAmount int64
+}
+
+// BakerTotals sums the current delegators of a baker
+type BakerTotals struct {
+	Delegators  int64
+	TotalAmount int64
 }
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// DelegationsFilter selects the delegations returned by GetDelegations, the bounds left unset don't filter anything
+type DelegationsFilter struct {
+	// Year keeps the delegations of the year in UTC, 0 means any year
+	Year int
+	// From and To bound the timestamps of the delegations to [From, To[, zero means no bound
+	From time.Time
+	To   time.Time
+	// BlockFrom and BlockTo bound the blocks of the delegations to [BlockFrom, BlockTo]
+	BlockFrom *int32
+	BlockTo   *int32
+	// MinAmount and MaxAmount bound the amounts of the delegations to [MinAmount, MaxAmount]
+	MinAmount *int64
+	MaxAmount *int64
+}
+
+// MinYear is the first year of delegations, the mainnet launched in 2018
+const MinYear = 2018
+
+// Validate checks the bounds of the filter, that every range starts before it ends and that the time range overlaps
+// the year. Both the REST and the gRPC api rely on it.
+func (f DelegationsFilter) Validate() error {
+	if f.Year != 0 && f.Year < MinYear {
+		return fmt.Errorf("year must be %d or later", MinYear)
+	}
+	for _, block := range []struct {
+		name  string
+		value *int32
+	}{{"block_from", f.BlockFrom}, {"block_to", f.BlockTo}} {
+		if block.value != nil && *block.value < 0 {
+			return fmt.Errorf("%s must not be negative", block.name)
+		}
+	}
+	for _, amount := range []struct {
+		name  string
+		value *int64
+	}{{"min_amount", f.MinAmount}, {"max_amount", f.MaxAmount}} {
+		if amount.value != nil && *amount.value < 0 {
+			return fmt.Errorf("%s must not be negative", amount.name)
+		}
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return errors.New("from must be before to")
+	}
+	if f.Year != 0 {
+		yearStart := time.Date(f.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		yearEnd := yearStart.AddDate(1, 0, 0)
+		if (!f.From.IsZero() && !f.From.Before(yearEnd)) || (!f.To.IsZero() && !f.To.After(yearStart)) {
+			return errors.New("from and to must overlap the year")
+		}
+	}
+	if f.BlockFrom != nil && f.BlockTo != nil && *f.BlockFrom > *f.BlockTo {
+		return errors.New("block_from must not be after block_to")
+	}
+	if f.MinAmount != nil && f.MaxAmount != nil && *f.MinAmount > *f.MaxAmount {
+		return errors.New("min_amount must not be greater than max_amount")
+	}
+	return nil
+}
+
+// scope adds the conditions of the filter to a query on the delegations, it can be combined with other scopes
+func (f DelegationsFilter) scope(tx *gorm.DB) *gorm.DB {
+	if f.Year != 0 {
+		// a range on the timestamp can use its index, unlike EXTRACT
+		tx = tx.Where("timestamp >= ? AND timestamp < ?",
+			time.Date(f.Year, time.January, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(f.Year+1, time.January, 1, 0, 0, 0, 0, time.UTC))
+	}
+	if !f.From.IsZero() {
+		tx = tx.Where("timestamp >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		tx = tx.Where("timestamp < ?", f.To)
+	}
+	if f.BlockFrom != nil {
+		tx = tx.Where("block >= ?", *f.BlockFrom)
+	}
+	if f.BlockTo != nil {
+		tx = tx.Where("block <= ?", *f.BlockTo)
+	}
+	if f.MinAmount != nil {
+		tx = tx.Where("amount >= ?", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		tx = tx.Where("amount <= ?", *f.MaxAmount)
+	}
+	return tx
+}
+
+// exportBatchSize is the number of delegations fetched at once from the cursor of an export
+const exportBatchSize = 1000
+
+// ExportDelegations reads the delegations selected by the filter, from the newest to the oldest, through a cursor
+// and hands them to write a batch at a time, the whole export is never held in memory.
+// An error returned by write stops the export and is returned.
+func (db *DbStore) ExportDelegations(ctx context.Context, filter DelegationsFilter, write func([]Delegations) error) error {
+	query, args := exportQuery(db.DB, filter)
+
+	// a cursor only lives in a transaction, all the batches are read from its snapshot
+	tx, err := db.pgxPool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// utility statements can't be prepared, the arguments are sanitized by pgx instead
+	if _, err := tx.Exec(ctx, "DECLARE delegations_export NO SCROLL CURSOR FOR "+query, append([]any{pgx.QueryExecModeSimpleProtocol}, args...)...); err != nil {
+		return err
+	}
+	fetch := fmt.Sprintf("FETCH %d FROM delegations_export", exportBatchSize)
+	for {
+		rows, err := tx.Query(ctx, fetch, pgx.QueryExecModeSimpleProtocol)
+		if err != nil {
+			return err
+		}
+		batch, err := pgx.CollectRows(rows, scanExportedDelegation)
+		if err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			if err := write(batch); err != nil {
+				return err
+			}
+		}
+		if len(batch) < exportBatchSize {
+			return tx.Commit(ctx)
+		}
+	}
+}
+
+// exportColumns are the columns of the delegations read by ExportDelegations, in the order of scanExportedDelegation
+const exportColumns = "id, delegator, timestamp, block, amount, new_delegate, prev_delegate"
+
+// exportQuery builds the query of the cursor of an export, with the same filters and order as GetDelegations
+func exportQuery(tx *gorm.DB, filter DelegationsFilter) (string, []any) {
+	var delegations []Delegations
+	stmt := tx.Session(&gorm.Session{DryRun: true}).
+		Select(exportColumns).
+		Scopes(filter.scope).
+		Order("block DESC, id DESC").
+		Find(&delegations).Statement
+	return stmt.SQL.String(), stmt.Vars
+}
+
+func scanExportedDelegation(row pgx.CollectableRow) (Delegations, error) {
+	var delegation Delegations
+	err := row.Scan(&delegation.ID, &delegation.Delegator, &delegation.Timestamp, &delegation.Block, &delegation.Amount, &delegation.NewDelegate, &delegation.PrevDelegate)
+	return delegation, err
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRunDB builds the SQL of the queries without connecting to a database
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gormDB
+}
+
+func TestDelegationsFilterScope(t *testing.T) {
+	blockFrom, blockTo := int32(100), int32(200)
+	minAmount, maxAmount := int64(0), int64(5000)
+	filter := DelegationsFilter{
+		Year:      2024,
+		From:      time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		BlockFrom: &blockFrom,
+		BlockTo:   &blockTo,
+		MinAmount: &minAmount,
+		MaxAmount: &maxAmount,
+	}
+
+	var delegations []Delegations
+	stmt := paginate(dryRunDB(t).Scopes(filter.scope), nil, 10).Find(&delegations).Statement
+	sql := stmt.SQL.String()
+	for _, condition := range []string{
+		"timestamp >= $1 AND timestamp < $2",
+		"timestamp >= $3",
+		"block >= $4",
+		"block <= $5",
+		"amount >= $6",
+		"amount <= $7",
+	} {
+		if !strings.Contains(sql, condition) {
+			t.Errorf("expected %q in %s", condition, sql)
+		}
+	}
+	if strings.Contains(sql, "timestamp < $3") {
+		t.Errorf("expected no upper bound on the timestamp besides the year, got %s", sql)
+	}
+	if len(stmt.Vars) != 8 {
+		t.Fatalf("expected 7 parameters and the limit, got %v", stmt.Vars)
+	}
+	if yearEnd := stmt.Vars[1].(time.Time); !yearEnd.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the year to end on 2025-01-01, got %v", yearEnd)
+	}
+	if stmt.Vars[5] != int64(0) {
+		t.Errorf("expected a zero min amount to be kept, got %v", stmt.Vars[5])
+	}
+}
+
+func TestDelegationsFilterScopeEmpty(t *testing.T) {
+	var delegations []Delegations
+	sql := paginate(dryRunDB(t).Scopes(DelegationsFilter{}.scope), nil, 10).Find(&delegations).Statement.SQL.String()
+	if strings.Contains(sql, "WHERE") {
+		t.Errorf("expected no condition, got %s", sql)
+	}
+}
+
+func TestDelegationsFilterValidate(t *testing.T) {
+	low, high, negativeBlock := int32(100), int32(200), int32(-1)
+	minAmount, maxAmount, negativeAmount := int64(10), int64(5), int64(-1)
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name   string
+		filter DelegationsFilter
+		valid  bool
+	}{
+		{"empty", DelegationsFilter{}, true},
+		{"time range in the year", DelegationsFilter{Year: 2024, From: march, To: march.AddDate(0, 1, 0)}, true},
+		{"from after to", DelegationsFilter{From: march, To: march}, false},
+		{"from after the year", DelegationsFilter{Year: 2023, From: march}, false},
+		{"to before the year", DelegationsFilter{Year: 2025, To: march}, false},
+		{"block range", DelegationsFilter{BlockFrom: &low, BlockTo: &high}, true},
+		{"reversed block range", DelegationsFilter{BlockFrom: &high, BlockTo: &low}, false},
+		{"reversed amount range", DelegationsFilter{MinAmount: &minAmount, MaxAmount: &maxAmount}, false},
+		{"first year", DelegationsFilter{Year: MinYear}, true},
+		{"year before the mainnet", DelegationsFilter{Year: 2017}, false},
+		{"negative block_from", DelegationsFilter{BlockFrom: &negativeBlock}, false},
+		{"negative block_to", DelegationsFilter{BlockTo: &negativeBlock}, false},
+		{"negative min_amount", DelegationsFilter{MinAmount: &negativeAmount}, false},
+		{"negative max_amount", DelegationsFilter{MaxAmount: &negativeAmount}, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.filter.Validate(); (err == nil) != test.valid {
+				t.Errorf("expected valid=%v, got %v", test.valid, err)
+			}
+		})
+	}
+}
+
+func TestExportQuery(t *testing.T) {
+	minAmount := int64(1000)
+	query, args := exportQuery(dryRunDB(t), DelegationsFilter{MinAmount: &minAmount})
+
+	expected := "SELECT " + exportColumns + ` FROM "delegations" WHERE amount >= $1 ORDER BY block DESC, id DESC`
+	if query != expected {
+		t.Errorf("expected %s, got %s", expected, query)
+	}
+	if len(args) != 1 || args[0] != minAmount {
+		t.Errorf("expected the min amount as only argument, got %v", args)
+	}
+}
@@ -0,0 +1,215 @@
+package delegationswatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// backfill stores the delegations of the blocks after fromLevel one page after the other,
+// the backfill checkpoint moves after every page so that an interrupted backfill resumes after the last stored page.
+// The initial sync from block 0 is silent, a backfill catching up after a downtime notifies the delegations it stores.
+func (dw *DelegationsWatcher) backfill(ctx context.Context, fromLevel int32) error {
+	if fromLevel == 0 {
+		log.Info("No blocks recorded in the database, query all delegations ...")
+	} else {
+		log.Infof("Last block recorded in the database: %v, getting delegations from last block to current state", fromLevel)
+	}
+
+	count := 0
+	err := dw.source.Delegations(ctx, fromLevel, 0, func(delegations []types.TzktDelegationsResponse) error {
+		if err := dw.storeBackfillPage(ctx, delegations, fromLevel > 0); err != nil {
+			return err
+		}
+		count += len(delegations)
+		log.Infof("%v delegations inserted into database", count)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("backfill stopped after %v delegations: %w", count, err)
+	}
+
+	log.Infof("All %v delegations inserted into database", count)
+	return nil
+}
+
+// storeBackfillPage inserts a page of delegations and moves the backfill checkpoint to the last block fully retrieved,
+// the delegations inserted are notified if asked to
+func (dw *DelegationsWatcher) storeBackfillPage(ctx context.Context, delegations []types.TzktDelegationsResponse, notify bool) error {
+	inserted, err := bulkInsertDelegations(ctx, dw.db, delegations, &db.SyncState{Stream: db.SyncStreamBackfill, Level: lastCompleteLevel(delegations)})
+	if err != nil {
+		return err
+	}
+	if notify {
+		dw.notify(ctx, inserted)
+	}
+	return nil
+}
+
+// lastCompleteLevel returns the highest block whose delegations are all in the page
+func lastCompleteLevel(delegations []types.TzktDelegationsResponse) int32 {
+	level := highestLevel(delegations)
+	// delegations of the last block of a full page may continue on the next page
+	if len(delegations) == pageLimit {
+		level--
+	}
+	return level
+}
+
+// chunkResult is sent by a backfill worker once it is done with a chunk
+type chunkResult struct {
+	chunk db.BackfillChunk
+	err   error
+}
+
+// parallelBackfill splits the blocks after fromLevel into chunks fetched by concurrent workers.
+// Completed chunks are recorded in the database, an interrupted backfill only fetches the chunks that are missing.
+// The backfill checkpoint moves to the end of the completed chunks that follow fromLevel without gap.
+// Once ctx is cancelled no new chunk is started, the chunks in progress stop after their current page.
+// Like backfill, only a backfill resuming after block 0 notifies the delegations it stores.
+func (dw *DelegationsWatcher) parallelBackfill(ctx context.Context, fromLevel int32) error {
+	chunks, err := dw.backfillChunks(ctx, fromLevel)
+	if err != nil {
+		return err
+	}
+
+	var pending []db.BackfillChunk
+	for _, chunk := range chunks {
+		if !chunk.Done {
+			pending = append(pending, chunk)
+		}
+	}
+	log.Infof("Backfilling delegations after block %v: %v chunks missing out of %v, %v workers", fromLevel, len(pending), len(chunks), dw.config.Backfill.Workers)
+
+	jobs := make(chan db.BackfillChunk)
+	results := make(chan chunkResult)
+	var wg sync.WaitGroup
+	for range dw.config.Backfill.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range jobs {
+				results <- chunkResult{chunk: chunk, err: dw.backfillChunk(ctx, chunk, fromLevel > 0)}
+			}
+		}()
+	}
+	go func() {
+	feed:
+		for _, chunk := range pending {
+			select {
+			case jobs <- chunk:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	var errs []error
+	for result := range results {
+		if result.err != nil {
+			log.Errorf("Failed to backfill blocks %v to %v: %v", result.chunk.FromLevel+1, result.chunk.ToLevel, result.err)
+			errs = append(errs, result.err)
+			continue
+		}
+		log.Infof("Blocks %v to %v backfilled", result.chunk.FromLevel+1, result.chunk.ToLevel)
+		markChunkDone(chunks, result.chunk.FromLevel)
+
+		if level := completedLevel(chunks, fromLevel); level > fromLevel {
+			writeCtx, cancel := writeContext(ctx)
+			err := dw.db.UpdateSyncLevel(writeCtx, db.SyncStreamBackfill, level)
+			cancel()
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%v chunks failed: %w", len(errs), errors.Join(errs...))
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	log.Infof("Backfill done up to block %v", completedLevel(chunks, fromLevel))
+	return nil
+}
+
+// backfillChunks returns the chunks of an interrupted backfill followed by new chunks up to the current head,
+// the chunks planned by the interrupted backfill may end well before it
+func (dw *DelegationsWatcher) backfillChunks(ctx context.Context, fromLevel int32) ([]db.BackfillChunk, error) {
+	chunks, err := dw.db.GetBackfillChunks(ctx, fromLevel)
+	if err != nil {
+		return nil, err
+	}
+	headLevel, err := dw.source.HeadLevel(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get head: %w", err)
+	}
+
+	plannedLevel := fromLevel
+	if len(chunks) > 0 {
+		plannedLevel = chunks[len(chunks)-1].ToLevel
+	}
+	planned := planChunks(plannedLevel, headLevel, dw.config.Backfill.ChunkSize)
+	if err := dw.db.CreateBackfillChunks(ctx, planned); err != nil {
+		return nil, err
+	}
+	return append(chunks, planned...), nil
+}
+
+// backfillChunk stores the delegations of the chunk and records its completion, the delegations inserted are notified if asked to
+func (dw *DelegationsWatcher) backfillChunk(ctx context.Context, chunk db.BackfillChunk, notify bool) error {
+	err := dw.source.Delegations(ctx, chunk.FromLevel, chunk.ToLevel, func(delegations []types.TzktDelegationsResponse) error {
+		inserted, err := bulkInsertDelegations(ctx, dw.db, delegations, nil)
+		if err != nil {
+			return err
+		}
+		if notify {
+			dw.notify(ctx, inserted)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	writeCtx, cancel := writeContext(ctx)
+	defer cancel()
+	return dw.db.CompleteBackfillChunk(writeCtx, chunk.FromLevel)
+}
+
+// planChunks splits ]fromLevel, toLevel] into chunks of chunkSize blocks
+func planChunks(fromLevel, toLevel, chunkSize int32) []db.BackfillChunk {
+	var chunks []db.BackfillChunk
+	for level := fromLevel; level < toLevel; level += chunkSize {
+		chunks = append(chunks, db.BackfillChunk{FromLevel: level, ToLevel: min(level+chunkSize, toLevel)})
+	}
+	return chunks
+}
+
+func markChunkDone(chunks []db.BackfillChunk, fromLevel int32) {
+	for i := range chunks {
+		if chunks[i].FromLevel == fromLevel {
+			chunks[i].Done = true
+		}
+	}
+}
+
+// completedLevel returns the end of the done chunks following fromLevel without gap, chunks are sorted by level
+func completedLevel(chunks []db.BackfillChunk, fromLevel int32) int32 {
+	level := fromLevel
+	for _, chunk := range chunks {
+		if !chunk.Done || chunk.FromLevel > level {
+			break
+		}
+		level = max(level, chunk.ToLevel)
+	}
+	return level
+}
@@ -0,0 +1,379 @@
+package delegationswatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ibraheemacara/tezos-delegation-service/broker"
+	"github.com/ibraheemacara/tezos-delegation-service/config"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/httpclient"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+// MockChunksDB is safe for concurrent use by the backfill workers
+type MockChunksDB struct {
+	db.DBInterface
+	mu          sync.Mutex
+	Chunks      []db.BackfillChunk
+	Completed   []int32
+	Inserted    int
+	SyncLevels  []int32
+	CompleteErr error
+}
+
+func (m *MockChunksDB) GetBackfillChunks(ctx context.Context, afterLevel int32) ([]db.BackfillChunk, error) {
+	return m.Chunks, nil
+}
+func (m *MockChunksDB) CreateBackfillChunks(ctx context.Context, chunks []db.BackfillChunk) error {
+	m.Chunks = append(m.Chunks, chunks...)
+	return nil
+}
+func (m *MockChunksDB) CompleteBackfillChunk(ctx context.Context, fromLevel int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Completed = append(m.Completed, fromLevel)
+	return m.CompleteErr
+}
+func (m *MockChunksDB) BulkInsertDelegations(ctx context.Context, delegations []db.Delegations, checkpoint *db.SyncState) ([]db.Delegations, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Inserted += len(delegations)
+	return delegations, nil
+}
+func (m *MockChunksDB) UpdateSyncLevel(ctx context.Context, stream string, level int32) error {
+	m.SyncLevels = append(m.SyncLevels, level)
+	return nil
+}
+
+// MockTzktHTTPClient returns the head level and one delegation for each range of blocks
+type MockTzktHTTPClient struct {
+	httpclient.HttpInterface
+	mu        sync.Mutex
+	headLevel int32
+	urls      []string
+}
+
+func (m *MockTzktHTTPClient) Get(ctx context.Context, url string) ([]byte, error) {
+	m.mu.Lock()
+	m.urls = append(m.urls, url)
+	m.mu.Unlock()
+	if strings.HasSuffix(url, "/v1/head") {
+		return json.Marshal(map[string]int32{"level": m.headLevel})
+	}
+	return json.Marshal([]types.TzktDelegationsResponse{{Id: 1, Level: 1}})
+}
+func (m *MockTzktHTTPClient) Stream(ctx context.Context, url string, read func(io.Reader) error) error {
+	return streamGet(m.Get(ctx, url))(read)
+}
+
+func TestPlanChunks(t *testing.T) {
+	chunks := planChunks(0, 250, 100)
+	expected := []db.BackfillChunk{{FromLevel: 0, ToLevel: 100}, {FromLevel: 100, ToLevel: 200}, {FromLevel: 200, ToLevel: 250}}
+	if len(chunks) != len(expected) {
+		t.Fatalf("expected chunks %v, got %v", expected, chunks)
+	}
+	for i := range expected {
+		if chunks[i] != expected[i] {
+			t.Errorf("expected chunk %v, got %v", expected[i], chunks[i])
+		}
+	}
+
+	if chunks := planChunks(250, 250, 100); len(chunks) != 0 {
+		t.Errorf("expected no chunk, got %v", chunks)
+	}
+}
+
+func TestCompletedLevel(t *testing.T) {
+	chunks := []db.BackfillChunk{
+		{FromLevel: 0, ToLevel: 100, Done: true},
+		{FromLevel: 100, ToLevel: 200},
+		{FromLevel: 200, ToLevel: 250, Done: true},
+	}
+	if level := completedLevel(chunks, 0); level != 100 {
+		t.Errorf("expected completed level 100, got %d", level)
+	}
+	chunks[1].Done = true
+	if level := completedLevel(chunks, 0); level != 250 {
+		t.Errorf("expected completed level 250, got %d", level)
+	}
+}
+
+func TestParallelBackfill(t *testing.T) {
+	mockDB := &MockChunksDB{}
+	httpClient := &MockTzktHTTPClient{headLevel: 250}
+	cfg := config.Config{}
+	cfg.Tzkt.Url = "http://fake-tzkt"
+	cfg.Backfill.Workers = 2
+	cfg.Backfill.ChunkSize = 100
+
+	watcher := &DelegationsWatcher{config: cfg, source: newTzktSource(cfg.Tzkt.Url, httpClient), db: mockDB}
+	if err := watcher.parallelBackfill(context.Background(), 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(mockDB.Chunks) != 3 {
+		t.Fatalf("expected 3 planned chunks, got %v", mockDB.Chunks)
+	}
+	if len(mockDB.Completed) != 3 || mockDB.Inserted != 3 {
+		t.Errorf("expected 3 chunks completed and 3 delegations inserted, got %v and %d", mockDB.Completed, mockDB.Inserted)
+	}
+	if last := mockDB.SyncLevels[len(mockDB.SyncLevels)-1]; last != 250 {
+		t.Errorf("expected backfill level 250, got %d", last)
+	}
+}
+
+func TestParallelBackfillResume(t *testing.T) {
+	mockDB := &MockChunksDB{
+		Chunks: []db.BackfillChunk{
+			{FromLevel: 0, ToLevel: 100, Done: true},
+			{FromLevel: 100, ToLevel: 200},
+			{FromLevel: 200, ToLevel: 250, Done: true},
+		},
+	}
+	httpClient := &MockTzktHTTPClient{headLevel: 250}
+	cfg := config.Config{}
+	cfg.Tzkt.Url = "http://fake-tzkt"
+	cfg.Backfill.Workers = 2
+	cfg.Backfill.ChunkSize = 100
+
+	watcher := &DelegationsWatcher{config: cfg, source: newTzktSource(cfg.Tzkt.Url, httpClient), db: mockDB}
+	if err := watcher.parallelBackfill(context.Background(), 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// only the head and the missing chunk are fetched
+	expectedUrl := "http://fake-tzkt/v1/operations/delegations?limit=10000&sort.asc=id&id.gt=0&level.gt=100&level.le=200"
+	if len(httpClient.urls) != 2 || httpClient.urls[0] != "http://fake-tzkt/v1/head" || httpClient.urls[1] != expectedUrl {
+		t.Errorf("expected the head then a single call to %s, got %v", expectedUrl, httpClient.urls)
+	}
+	if len(mockDB.SyncLevels) != 1 || mockDB.SyncLevels[0] != 250 {
+		t.Errorf("expected backfill level 250, got %v", mockDB.SyncLevels)
+	}
+}
+
+func TestParallelBackfillResumeToHead(t *testing.T) {
+	// the interrupted backfill planned its chunks up to block 200, the head moved on since
+	mockDB := &MockChunksDB{
+		Chunks: []db.BackfillChunk{
+			{FromLevel: 100, ToLevel: 200, Done: true},
+		},
+	}
+	cfg := config.Config{}
+	// MockSource is not safe for concurrent use
+	cfg.Backfill.Workers = 1
+	cfg.Backfill.ChunkSize = 100
+	source := &MockSource{head: 350, failAfter: -1}
+
+	watcher := &DelegationsWatcher{config: cfg, source: source, db: mockDB}
+	if err := watcher.parallelBackfill(context.Background(), 100); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []db.BackfillChunk{{FromLevel: 100, ToLevel: 200, Done: true}, {FromLevel: 200, ToLevel: 300}, {FromLevel: 300, ToLevel: 350}}
+	if len(mockDB.Chunks) != len(expected) {
+		t.Fatalf("expected chunks %v, got %v", expected, mockDB.Chunks)
+	}
+	for i := range expected {
+		if mockDB.Chunks[i] != expected[i] {
+			t.Errorf("expected chunk %v, got %v", expected[i], mockDB.Chunks[i])
+		}
+	}
+	if mockDB.Inserted != 150 {
+		t.Errorf("expected the 150 blocks after the planned chunks to be fetched, got %d delegations", mockDB.Inserted)
+	}
+	if last := mockDB.SyncLevels[len(mockDB.SyncLevels)-1]; last != 350 {
+		t.Errorf("expected backfill level 350, got %d", last)
+	}
+}
+
+func TestParallelBackfillError(t *testing.T) {
+	mockDB := &MockChunksDB{CompleteErr: fmt.Errorf("complete error")}
+	httpClient := &MockTzktHTTPClient{headLevel: 250}
+	cfg := config.Config{}
+	cfg.Tzkt.Url = "http://fake-tzkt"
+	cfg.Backfill.Workers = 2
+	cfg.Backfill.ChunkSize = 100
+
+	watcher := &DelegationsWatcher{config: cfg, source: newTzktSource(cfg.Tzkt.Url, httpClient), db: mockDB}
+	if err := watcher.parallelBackfill(context.Background(), 0); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if len(mockDB.SyncLevels) != 0 {
+		t.Errorf("expected backfill level not to move, got %v", mockDB.SyncLevels)
+	}
+}
+
+func TestParallelBackfillCancelled(t *testing.T) {
+	mockDB := &MockChunksDB{}
+	httpClient := &MockTzktHTTPClient{headLevel: 250}
+	cfg := config.Config{}
+	cfg.Tzkt.Url = "http://fake-tzkt"
+	cfg.Backfill.Workers = 2
+	cfg.Backfill.ChunkSize = 100
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	watcher := &DelegationsWatcher{config: cfg, source: newTzktSource(cfg.Tzkt.Url, httpClient), db: mockDB}
+	if err := watcher.parallelBackfill(ctx, 0); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if len(mockDB.Completed) != 0 {
+		t.Errorf("expected no chunk to complete, got %v", mockDB.Completed)
+	}
+}
+
+// MockSlowInsertDB holds an insert until released, the insert fails like pgx when its context is cancelled by then
+type MockSlowInsertDB struct {
+	MockDB
+	inserting chan struct{}
+	release   chan struct{}
+}
+
+func (m *MockSlowInsertDB) BulkInsertDelegations(ctx context.Context, delegations []db.Delegations, checkpoint *db.SyncState) ([]db.Delegations, error) {
+	close(m.inserting)
+	<-m.release
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, fmt.Errorf("expected the insert to be bounded by a timeout")
+	}
+	return m.MockDB.BulkInsertDelegations(ctx, delegations, checkpoint)
+}
+
+func TestStoreBackfillPageShutdown(t *testing.T) {
+	mockDB := &MockSlowInsertDB{inserting: make(chan struct{}), release: make(chan struct{})}
+	watcher := &DelegationsWatcher{db: mockDB}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- watcher.storeBackfillPage(ctx, []types.TzktDelegationsResponse{{Id: 1, Level: 5}}, false)
+	}()
+	<-mockDB.inserting
+	// the shutdown starts while the page is being copied
+	cancel()
+	close(mockDB.release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected the page to be stored, got %v", err)
+	}
+	if len(mockDB.BulkInsertDelegs) != 1 || len(mockDB.Checkpoints) != 1 || mockDB.Checkpoints[0].Level != 5 {
+		t.Errorf("expected the page and its checkpoint to be committed, got %v and %v", mockDB.BulkInsertDelegs, mockDB.Checkpoints)
+	}
+}
+
+func TestStoreBackfillPage(t *testing.T) {
+	mockDB := &MockDB{}
+	watcher := &DelegationsWatcher{db: mockDB}
+
+	page := []types.TzktDelegationsResponse{{Level: 5}, {Level: 7}}
+	if err := watcher.storeBackfillPage(context.Background(), page, false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// the last block of a full page may continue on the next page
+	fullPage := make([]types.TzktDelegationsResponse, pageLimit)
+	for i := range fullPage {
+		fullPage[i].Level = 10
+	}
+	fullPage[0].Level = 8
+	if err := watcher.storeBackfillPage(context.Background(), fullPage, false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []db.SyncState{{Stream: db.SyncStreamBackfill, Level: 7}, {Stream: db.SyncStreamBackfill, Level: 9}}
+	if len(mockDB.Checkpoints) != len(expected) {
+		t.Fatalf("expected checkpoints %v, got %v", expected, mockDB.Checkpoints)
+	}
+	for i := range expected {
+		if mockDB.Checkpoints[i] != expected[i] {
+			t.Errorf("expected checkpoint %v, got %v", expected[i], mockDB.Checkpoints[i])
+		}
+	}
+}
+
+func TestBackfillFromLiveCheckpoint(t *testing.T) {
+	source := &MockSource{failAfter: -1}
+	for _, levels := range []map[string]int32{
+		{db.SyncStreamBackfill: 5, db.SyncStreamLive: 20},
+		{db.SyncStreamBackfill: 20, db.SyncStreamLive: 5},
+	} {
+		watcher := &DelegationsWatcher{source: source, db: &MockDB{SyncLevels: levels}}
+		if err := watcher.backfillFromCheckpoint(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	// the blocks stored by the watcher are not fetched again
+	if len(source.fromLevels) != 2 || source.fromLevels[0] != 20 || source.fromLevels[1] != 20 {
+		t.Errorf("expected the backfills to resume after block 20, got %v", source.fromLevels)
+	}
+}
+
+func TestBackfillWithoutCheckpoint(t *testing.T) {
+	// delegations stored before the checkpoints were recorded, the streams are at level 0
+	source := &MockSource{failAfter: -1}
+	mockDB := &MockDB{SyncLevels: map[string]int32{}, StoredBlock: 42}
+	watcher := &DelegationsWatcher{source: source, db: mockDB}
+	if err := watcher.backfillFromCheckpoint(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(source.fromLevels) != 1 || source.fromLevels[0] != 42 {
+		t.Errorf("expected the backfill to resume after block 42, got %v", source.fromLevels)
+	}
+	if len(mockDB.Checkpoints) != 1 || mockDB.Checkpoints[0] != (db.SyncState{Stream: db.SyncStreamBackfill, Level: 42}) {
+		t.Errorf("expected the backfill checkpoint to be recorded at block 42, got %v", mockDB.Checkpoints)
+	}
+
+	// an interrupted parallel backfill stores its chunks without checkpoint, it resumes from them
+	source = &MockSource{failAfter: -1}
+	watcher.source = source
+	watcher.db = &MockDB{SyncLevels: map[string]int32{}, StoredBlock: 42, Chunks: []db.BackfillChunk{{FromLevel: 40, ToLevel: 50}}}
+	if err := watcher.backfillFromCheckpoint(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(source.fromLevels) != 1 || source.fromLevels[0] != 0 {
+		t.Errorf("expected the backfill to start from block 0, got %v", source.fromLevels)
+	}
+}
+
+func TestBackfillNotifiesAfterDowntime(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		fromLevel int32
+		workers   int
+		published int
+	}{
+		{"initial sync", 0, 1, 0},
+		{"after a downtime", 20, 1, 5},
+		{"parallel initial sync", 0, 2, 0},
+		{"parallel after a downtime", 20, 2, 5},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			delegationsBroker := broker.NewBroker()
+			subscription := delegationsBroker.Subscribe(broker.Filter{})
+			cfg := config.Config{}
+			cfg.Backfill.Workers = test.workers
+			cfg.Backfill.ChunkSize = 10
+			watcher := &DelegationsWatcher{
+				config: cfg,
+				source: &MockSource{head: test.fromLevel + 5, failAfter: -1},
+				db:     &MockDB{SyncLevels: map[string]int32{db.SyncStreamBackfill: test.fromLevel}},
+				broker: delegationsBroker,
+			}
+			if err := watcher.backfillFromCheckpoint(context.Background()); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(subscription.Events()) != test.published {
+				t.Errorf("expected %d delegations published, got %d", test.published, len(subscription.Events()))
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package delegationswatcher
+
+// maxTrackedBlocks is how many heads are remembered to detect reorganizations,
+// it is far above the depth of the reorganizations that can happen with tenderbake
+const maxTrackedBlocks = 64
+
+// blockTracker remembers the hashes of the last processed heads
+type blockTracker struct {
+	last   int32
+	levels []int32
+	hashes map[int32]string
+}
+
+// newBlockTracker creates a tracker resuming after lastLevel, whose hash is unknown
+func newBlockTracker(lastLevel int32) *blockTracker {
+	return &blockTracker{
+		last:   lastLevel,
+		hashes: make(map[int32]string),
+	}
+}
+
+// lastLevel returns the level of the last processed head, 0 if none was processed yet
+func (bt *blockTracker) lastLevel() int32 {
+	return bt.last
+}
+
+// hash returns the hash recorded for level
+func (bt *blockTracker) hash(level int32) (string, bool) {
+	hash, ok := bt.hashes[level]
+	return hash, ok
+}
+
+// add records a processed head, levels must be added in increasing order
+func (bt *blockTracker) add(level int32, hash string) {
+	bt.last = level
+	bt.levels = append(bt.levels, level)
+	bt.hashes[level] = hash
+	if len(bt.levels) > maxTrackedBlocks {
+		delete(bt.hashes, bt.levels[0])
+		bt.levels = bt.levels[1:]
+	}
+}
+
+// rollback forgets every head above level
+func (bt *blockTracker) rollback(level int32) {
+	for len(bt.levels) > 0 && bt.levels[len(bt.levels)-1] > level {
+		delete(bt.hashes, bt.levels[len(bt.levels)-1])
+		bt.levels = bt.levels[:len(bt.levels)-1]
+	}
+	bt.last = min(bt.last, level)
+}
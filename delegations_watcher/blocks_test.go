@@ -0,0 +1,46 @@
+package delegationswatcher
+
+import "testing"
+
+func TestBlockTracker(t *testing.T) {
+	blocks := newBlockTracker(9)
+	if blocks.lastLevel() != 9 {
+		t.Errorf("expected last level 9, got %d", blocks.lastLevel())
+	}
+
+	blocks.add(10, "BLa")
+	blocks.add(11, "BLb")
+	if blocks.lastLevel() != 11 {
+		t.Errorf("expected last level 11, got %d", blocks.lastLevel())
+	}
+	if hash, ok := blocks.hash(10); !ok || hash != "BLa" {
+		t.Errorf("expected hash BLa for level 10, got %s", hash)
+	}
+
+	blocks.rollback(10)
+	if blocks.lastLevel() != 10 {
+		t.Errorf("expected last level 10, got %d", blocks.lastLevel())
+	}
+	if _, ok := blocks.hash(11); ok {
+		t.Errorf("expected level 11 to be forgotten")
+	}
+
+	// rolling back below the tracked heads keeps the rollback level
+	blocks.rollback(5)
+	if blocks.lastLevel() != 5 {
+		t.Errorf("expected last level 5, got %d", blocks.lastLevel())
+	}
+}
+
+func TestBlockTrackerLimit(t *testing.T) {
+	blocks := newBlockTracker(0)
+	for level := int32(1); level <= maxTrackedBlocks+1; level++ {
+		blocks.add(level, "BL")
+	}
+	if _, ok := blocks.hash(1); ok {
+		t.Errorf("expected oldest level to be forgotten")
+	}
+	if len(blocks.hashes) != maxTrackedBlocks {
+		t.Errorf("expected %d tracked blocks, got %d", maxTrackedBlocks, len(blocks.hashes))
+	}
+}
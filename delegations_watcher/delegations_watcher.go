@@ -4,95 +4,151 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"time"
 
 	events "github.com/dipdup-net/go-lib/tzkt/events"
+	"github.com/ibraheemacara/tezos-delegation-service/broker"
 	"github.com/ibraheemacara/tezos-delegation-service/config"
 	"github.com/ibraheemacara/tezos-delegation-service/db"
 	"github.com/ibraheemacara/tezos-delegation-service/httpclient"
 	"github.com/ibraheemacara/tezos-delegation-service/types"
+	"github.com/ibraheemacara/tezos-delegation-service/webhooks"
 	log "github.com/sirupsen/logrus"
 )
 
 type DelegationsWatcher struct {
-	config     config.Config
-	httpClient httpclient.HttpInterface
-	db         db.DBInterface
-	tzktClient TzktClient
+	config config.Config
+	source Source
+	db     db.DBInterface
+	heads  HeadsClient
+	// verifier, if any, cross-checks the delegations of the new blocks before they are stored
+	verifier Source
+	// broker, if any, receives the delegations of the new blocks once stored
+	broker *broker.Broker
+	// dispatcher, if any, posts the delegations of the new blocks to the webhooks once stored
+	dispatcher *webhooks.Dispatcher
+	// refreshes signals that the leaderboards are outdated, signals sent during a refresh are merged into the next one
+	refreshes chan struct{}
 }
 
-type TzktClient interface {
-	Connect(ctx context.Context) error
-	SubscribeToHead() error
-	Listen() <-chan events.Message
-}
-
-func NewDelegationsWatcher(config config.Config, httpClient httpclient.HttpInterface, db db.DBInterface) *DelegationsWatcher {
-	wsUrl := fmt.Sprintf("%s/v1/ws", config.Tzkt.Url)
-	return &DelegationsWatcher{
-		config:     config,
-		httpClient: httpClient,
+func NewDelegationsWatcher(cfg config.Config, httpClient httpclient.HttpInterface, db db.DBInterface, broker *broker.Broker, dispatcher *webhooks.Dispatcher) *DelegationsWatcher {
+	watcher := &DelegationsWatcher{
+		config:     cfg,
 		db:         db,
-		tzktClient: events.NewTzKT(wsUrl),
+		broker:     broker,
+		dispatcher: dispatcher,
+		refreshes:  make(chan struct{}, 1),
+	}
+	if cfg.Source == config.SourceNode {
+		source := newNodeSource(cfg.Node.Url, httpClient)
+		watcher.source = source
+		watcher.heads = newNodeHeads(source, time.Duration(cfg.Node.PollInterval)*time.Second)
+	} else {
+		sources := make([]Source, len(cfg.Tzkt.Urls))
+		heads := make([]HeadsClient, len(cfg.Tzkt.Urls))
+		for i, url := range cfg.Tzkt.Urls {
+			sources[i] = newTzktSource(url, httpClient)
+			heads[i] = events.NewTzKT(fmt.Sprintf("%s/v1/ws", url))
+		}
+		source := newFailoverSource(cfg.Tzkt.Urls, sources)
+		watcher.source = source
+		watcher.heads = newFailoverHeads(cfg.Tzkt.Urls, heads)
+		if cfg.Tzkt.Verify {
+			watcher.verifier = source.alternate()
+		}
 	}
+	return watcher
 }
 
-func (dw *DelegationsWatcher) Start() {
+// Start backfills the past delegations then watches new blocks, it returns once ctx is cancelled
+func (dw *DelegationsWatcher) Start(ctx context.Context) {
 	log.Info("Delegations watcher started")
 
-	//first we get the last block recorded in the database
-	lastBlock, err := dw.db.GetLastBlock()
+	refresherDone := make(chan struct{})
+	go func() {
+		defer close(refresherDone)
+		dw.refreshLeaderboards(ctx)
+	}()
+	defer func() { <-refresherDone }()
+
+	// a failed backfill is restarted from its last checkpoint until it completes
+	err := supervise(ctx, "Backfill", dw.backfillFromCheckpoint)
 	if err != nil {
-		log.Error("Failed to get last block from database: ", err)
+		log.Info("Backfill interrupted by shutdown")
 		return
 	}
+	// the leaderboards are refreshed once the backfill completes rather than after each of its pages
+	dw.requestLeaderboardsRefresh()
 
-	var allDelegations []types.TzktDelegationsResponse
-	if lastBlock == 0 {
-		log.Info("No blocks recorded in the database, query all delegations from tzkt ...")
+	//all past delegations are stored, start watching for new blocks from the last synced block
+	dw.WatchNewBlocks(ctx)
+}
 
-		allDelegations, err = getDelegations(dw.config.Tzkt.Url, 0, dw.httpClient)
+// backfillFromCheckpoint stores the delegations of the blocks after the last block fully processed by the backfill
+// or by the blocks watcher, the blocks stored by the watcher of a previous run are not fetched again
+func (dw *DelegationsWatcher) backfillFromCheckpoint(ctx context.Context) error {
+	var lastBlock int32
+	for _, stream := range []string{db.SyncStreamBackfill, db.SyncStreamLive} {
+		level, err := dw.db.GetSyncLevel(ctx, stream)
 		if err != nil {
-			log.Error("Failed to get delegations from tzkt: ", err)
-			return
+			return fmt.Errorf("failed to get %s sync level from database: %w", stream, err)
 		}
-
-	} else {
-		log.Infof("Last block recorded in the database: %v, getting delegations from last block to current state", lastBlock)
-		//get delegations from last block to current state
-		allDelegations, err = getDelegationsFromLevel(dw.config.Tzkt.Url, lastBlock, dw.httpClient)
+		lastBlock = max(lastBlock, level)
+	}
+	if lastBlock == 0 {
+		level, err := dw.uncheckpointedLevel(ctx)
 		if err != nil {
-			log.Error("Failed to get delegations from tzkt: ", err)
-			return
+			return err
 		}
+		lastBlock = level
 	}
 
-	//all past delegations are retrived from tzkt, start watching for new blocks
-	go dw.WatchNewBlocks(context.Background())
+	if dw.config.Backfill.Workers > 1 {
+		return dw.parallelBackfill(ctx, lastBlock)
+	}
+	return dw.backfill(ctx, lastBlock)
+}
 
-	//insert all delegations into database
-	log.Infof("Number of delegations: %v, inserting into database", len(allDelegations))
-	err = bulkInsertDelegations(dw.db, allDelegations)
+// uncheckpointedLevel returns the last stored block of a database filled before the checkpoints were recorded and
+// records it as the backfill checkpoint, 0 when the database is empty. The delegations of an interrupted parallel
+// backfill are stored without checkpoint, it resumes from its chunks instead.
+func (dw *DelegationsWatcher) uncheckpointedLevel(ctx context.Context) (int32, error) {
+	level, err := dw.db.GetLastBlock(ctx)
 	if err != nil {
-		log.Error("Failed to insert delegations into database: ", err)
-		return
+		return 0, fmt.Errorf("failed to get last block from database: %w", err)
+	}
+	if level == 0 {
+		return 0, nil
+	}
+	chunks, err := dw.db.GetBackfillChunks(ctx, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get backfill chunks from database: %w", err)
+	}
+	if len(chunks) > 0 {
+		return 0, nil
 	}
 
-	log.Infof("All %v delegations inserted into database", len(allDelegations))
-
-	// go dw.WatchNewBlocks()
+	log.Warnf("Delegations stored up to block %v without checkpoint, resuming after it", level)
+	writeCtx, cancel := writeContext(ctx)
+	defer cancel()
+	if err := dw.db.UpdateSyncLevel(writeCtx, db.SyncStreamBackfill, level); err != nil {
+		return 0, fmt.Errorf("failed to update backfill sync level: %w", err)
+	}
+	return level, nil
 }
 
 func (dw *DelegationsWatcher) WatchNewBlocks(ctx context.Context) {
 	log.Info("Start watching for new blocks...")
+	blocks := newBlockTracker(dw.lastSyncedLevel(ctx))
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
 		}
-		if err := dw.tzktClient.Connect(ctx); err != nil {
-			log.Errorf("Failed to connect to tzkt: %v, retrying in 5 seconds", err)
+		if err := dw.heads.Connect(ctx); err != nil {
+			log.Errorf("Failed to connect to the heads stream: %v, retrying in 5 seconds", err)
 			select {
 			case <-time.After(5 * time.Second):
 				continue
@@ -100,147 +156,353 @@ func (dw *DelegationsWatcher) WatchNewBlocks(ctx context.Context) {
 				return
 			}
 		}
-		log.Info("Connected to tzkt events hub")
+		log.Info("Connected to the heads stream")
 
 		//subscribe to head events
-		if err := dw.tzktClient.SubscribeToHead(); err != nil {
+		if err := dw.heads.SubscribeToHead(); err != nil {
 			log.Errorf("Failed to subscribe to head events: %v", err)
 		}
 
-		//process received messages
-		for msg := range dw.tzktClient.Listen() {
-			if msg.Channel == events.ChannelHead {
-				log.Info("Received head event")
-				raw, err := json.Marshal(msg.Body)
-				if err != nil {
-					log.Errorf("Failed to marshal head event: %v", err)
-					continue
-				}
-
-				var head map[string]any
-				err = json.Unmarshal(raw, &head)
-				if err != nil {
-					log.Errorf("Failed to unmarshal head event: %v", err)
-					continue
-				}
-
-				if head["level"] == nil {
-					continue
-				}
-				level := head["level"].(float64)
-
-				log.Infof("New block received: %v, getting delegations", level)
-
-				delegationsResponse, err := getDelegations(dw.config.Tzkt.Url, int32(level), dw.httpClient)
-				if err != nil {
-					log.Errorf("Failed to get delegations from tzkt: %v", err)
-					continue
-				}
-				if len(delegationsResponse) == 0 {
-					log.Infof("No delegations found for block: %v", level)
-					continue
-				}
-				log.Infof("Number of delegations: %v, inserting into database; this may take a while", len(delegationsResponse))
-				err = bulkInsertDelegations(dw.db, delegationsResponse)
-				if err != nil {
-					log.Errorf("Failed to insert delegations for block %v into database: %v", level, err)
-					continue
-				}
-
-				log.Infof("All %v delegations inserted into database for block %v", len(delegationsResponse), level)
-
+		//process received messages until the connection drops or ctx is cancelled
+		if !dw.listen(ctx, blocks) {
+			if err := dw.heads.Close(); err != nil {
+				log.Errorf("Failed to close the heads stream: %v", err)
 			}
+			log.Info("Stopped watching for new blocks")
+			return
 		}
 
 		// Reconnect logic
+		log.Println("disconnected, retrying in 5s…")
 		select {
+		case <-time.After(5 * time.Second):
 		case <-ctx.Done():
 			return
-		default:
-			log.Println("disconnected, retrying in 5s…")
-			time.Sleep(5 * time.Second)
 		}
 
 	}
 }
 
-func getDelegations(tzktUrl string, level int32, httpClient httpclient.HttpInterface) ([]types.TzktDelegationsResponse, error) {
-	limit := 10000
-	offset := 0
-	allDelegations := []types.TzktDelegationsResponse{}
+// listen processes the messages received from the heads stream, reorg notifications are sent on the head channel.
+// It returns false once ctx is cancelled and true when the connection dropped.
+func (dw *DelegationsWatcher) listen(ctx context.Context, blocks *blockTracker) bool {
+	messages := dw.heads.Listen()
 	for {
-		var url string
-		if level == 0 {
-			url = fmt.Sprintf("%s/v1/operations/delegations?limit=%d&offset=%d", tzktUrl, limit, offset)
-		} else {
-			url = fmt.Sprintf("%s/v1/operations/delegations?limit=%d&offset=%d&level=%d", tzktUrl, limit, offset, level)
+		var msg events.Message
+		select {
+		case <-ctx.Done():
+			return false
+		case m, ok := <-messages:
+			if !ok {
+				return true
+			}
+			msg = m
 		}
 
-		data, err := httpClient.Get(url)
+		if msg.Channel != events.ChannelHead {
+			continue
+		}
+		if msg.Type == events.MessageTypeReorg {
+			log.Warnf("Chain reorganization received, rolling back to block %v", msg.State)
+			if err := dw.rollback(ctx, blocks, int32(msg.State)); err != nil {
+				log.Errorf("Failed to roll back to block %v: %v", msg.State, err)
+			}
+			continue
+		}
+
+		log.Info("Received head event")
+		raw, err := json.Marshal(msg.Body)
 		if err != nil {
-			return nil, err
+			log.Errorf("Failed to marshal head event: %v", err)
+			continue
 		}
 
-		var delegations []types.TzktDelegationsResponse
-		err = json.Unmarshal(data, &delegations)
+		var head map[string]any
+		err = json.Unmarshal(raw, &head)
 		if err != nil {
-			return nil, err
+			log.Errorf("Failed to unmarshal head event: %v", err)
+			continue
 		}
 
-		if len(delegations) == 0 {
-			break
+		if head["level"] == nil {
+			continue
 		}
-		allDelegations = append(allDelegations, delegations...)
+		level := int32(head["level"].(float64))
+		hash, _ := head["hash"].(string)
 
-		offset += limit
+		if err := dw.processHead(ctx, blocks, level, hash); err != nil {
+			log.Errorf("Failed to process block %v: %v", level, err)
+		}
+	}
+}
 
+// processHead inserts the delegations of every block since the last processed head up to level.
+// A head replacing an already processed block means the chain was reorganized, the replaced blocks are rolled back first.
+func (dw *DelegationsWatcher) processHead(ctx context.Context, blocks *blockTracker, level int32, hash string) error {
+	if level <= blocks.lastLevel() {
+		knownHash, ok := blocks.hash(level)
+		if !ok || knownHash == hash {
+			log.Infof("Block %v already processed", level)
+			return nil
+		}
+		log.Warnf("Block %v replaced by %v, rolling back", level, hash)
+		if err := dw.rollback(ctx, blocks, level-1); err != nil {
+			return err
+		}
 	}
 
-	return allDelegations, nil
+	fromLevel := blocks.lastLevel()
+	if fromLevel == 0 {
+		fromLevel = level - 1
+	}
+
+	log.Infof("New block received: %v, getting delegations from block %v", level, fromLevel+1)
+	// each page is stored and notified as it arrives, the live checkpoint moves to the last block the page completes
+	count := 0
+	checkpointLevel := fromLevel
+	var unverified []types.TzktDelegationsResponse
+	err := dw.source.Delegations(ctx, fromLevel, level, func(delegations []types.TzktDelegationsResponse) error {
+		completeLevel := lastCompleteLevel(delegations)
+		inserted, err := bulkInsertDelegations(ctx, dw.db, delegations, &db.SyncState{Stream: db.SyncStreamLive, Level: completeLevel})
+		if err != nil {
+			return fmt.Errorf("failed to insert delegations into database: %w", err)
+		}
+		// a replayed block only notifies the delegations that were not stored yet
+		dw.notify(ctx, inserted)
+		count += len(delegations)
+		log.Infof("%v delegations inserted into database, %v of them new", len(delegations), len(inserted))
+
+		if completeLevel > checkpointLevel {
+			if dw.verifier != nil {
+				var verified []types.TzktDelegationsResponse
+				verified, unverified = splitAtLevel(append(unverified, delegations...), completeLevel)
+				dw.verify(ctx, checkpointLevel, completeLevel, verified)
+			}
+			checkpointLevel = completeLevel
+		} else if dw.verifier != nil {
+			unverified = append(unverified, delegations...)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ingest blocks %v to %v: %w", fromLevel+1, level, err)
+	}
+
+	// the blocks after the last page have no delegation
+	if checkpointLevel < level {
+		writeCtx, cancel := writeContext(ctx)
+		defer cancel()
+		if err := dw.db.UpdateSyncLevel(writeCtx, db.SyncStreamLive, level); err != nil {
+			return fmt.Errorf("failed to update live sync level: %w", err)
+		}
+		if dw.verifier != nil {
+			dw.verify(ctx, checkpointLevel, level, unverified)
+		}
+	}
+	blocks.add(level, hash)
+	if count == 0 {
+		log.Infof("No delegations found for block: %v", level)
+		return nil
+	}
+	dw.requestLeaderboardsRefresh()
+
+	log.Infof("All %v delegations inserted into database for block %v", count, level)
+	return nil
 }
 
-func getDelegationsFromLevel(tzktUrl string, fromLevel int32, httpClient httpclient.HttpInterface) ([]types.TzktDelegationsResponse, error) {
-	limit := 10000
-	offset := 0
-	allDelegations := []types.TzktDelegationsResponse{}
-	for {
-		url := fmt.Sprintf("%s/v1/operations/delegations?limit=%d&offset=%d&level.gt=%d", tzktUrl, limit, offset, fromLevel)
-		data, err := httpClient.Get(url)
+// splitAtLevel splits delegations sorted by block into the delegations up to level and the delegations after it
+func splitAtLevel(delegations []types.TzktDelegationsResponse, level int32) ([]types.TzktDelegationsResponse, []types.TzktDelegationsResponse) {
+	i := slices.IndexFunc(delegations, func(delegation types.TzktDelegationsResponse) bool { return delegation.Level > level })
+	if i < 0 {
+		return delegations, nil
+	}
+	return delegations[:i], slices.Clone(delegations[i:])
+}
+
+// lastSyncedLevel returns the highest block processed by the backfill or the blocks watcher
+func (dw *DelegationsWatcher) lastSyncedLevel(ctx context.Context) int32 {
+	var lastLevel int32
+	for _, stream := range []string{db.SyncStreamBackfill, db.SyncStreamLive} {
+		level, err := dw.db.GetSyncLevel(ctx, stream)
 		if err != nil {
-			return nil, err
+			log.Errorf("Failed to get %s sync level from database: %v", stream, err)
+			continue
+		}
+		lastLevel = max(lastLevel, level)
+	}
+	return lastLevel
+}
+
+// rollback deletes the delegations above level, they are ingested again with the next head
+func (dw *DelegationsWatcher) rollback(ctx context.Context, blocks *blockTracker, level int32) error {
+	deleted, err := dw.db.DeleteDelegationsAboveBlock(ctx, level)
+	if err != nil {
+		return err
+	}
+	blocks.rollback(level)
+	log.Infof("Rolled back to block %v, %v delegations deleted", level, deleted)
+	if deleted > 0 {
+		dw.requestLeaderboardsRefresh()
+	}
+	return nil
+}
+
+// requestLeaderboardsRefresh asks for a refresh of the leaderboards without waiting for it
+func (dw *DelegationsWatcher) requestLeaderboardsRefresh() {
+	select {
+	case dw.refreshes <- struct{}{}:
+	default:
+	}
+}
+
+// refreshLeaderboards refreshes the leaderboards when requested until ctx is cancelled. A single refresh runs
+// at a time, a refresh takes a while on a large database and the blocks keep coming meanwhile.
+func (dw *DelegationsWatcher) refreshLeaderboards(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-dw.refreshes:
+		}
+		start := time.Now()
+		if err := dw.db.RefreshLeaderboards(ctx); err != nil {
+			if ctx.Err() == nil {
+				log.Errorf("Failed to refresh the leaderboards: %v", err)
+			}
+			continue
 		}
+		log.Infof("Leaderboards refreshed in %v", time.Since(start))
+	}
+}
+
+// writeTimeout bounds a write to the database once the shutdown started
+const writeTimeout = 30 * time.Second
+
+// writeContext returns the context of a write to the database, it is not cancelled with ctx: a page of delegations
+// and its checkpoint are committed even when the shutdown starts meanwhile, the next page is not fetched.
+func writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), writeTimeout)
+}
 
+// pageLimit is the number of delegations requested to tzkt per page
+const pageLimit = 10000
+
+// getHeadLevel returns the level of the last block indexed by tzkt
+func getHeadLevel(ctx context.Context, tzktUrl string, httpClient httpclient.HttpInterface) (int32, error) {
+	var head struct {
+		Level int32 `json:"level"`
+	}
+	if err := httpclient.DecodeJSON(ctx, httpClient, fmt.Sprintf("%s/v1/head", tzktUrl), &head); err != nil {
+		return 0, err
+	}
+	return head.Level, nil
+}
+
+// getDelegations calls onPage with every page of delegations of the blocks in ]fromLevel, toLevel],
+// toLevel 0 means up to the current head. Pages are requested by increasing operation id which, unlike
+// offsets, stays stable while new delegations are added. Cancelling ctx aborts the request in flight and no other
+// page is requested, a page already handed to onPage is stored.
+func getDelegations(ctx context.Context, tzktUrl string, fromLevel, toLevel int32, httpClient httpclient.HttpInterface, onPage func([]types.TzktDelegationsResponse) error) error {
+	var lastId int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		url := fmt.Sprintf("%s/v1/operations/delegations?limit=%d&sort.asc=id&id.gt=%d&level.gt=%d", tzktUrl, pageLimit, lastId, fromLevel)
+		if toLevel > 0 {
+			url = fmt.Sprintf("%s&level.le=%d", url, toLevel)
+		}
+
+		// the page is decoded while it is received
 		var delegations []types.TzktDelegationsResponse
-		err = json.Unmarshal(data, &delegations)
+		err := httpclient.DecodeArray(ctx, httpClient, url, func(delegation types.TzktDelegationsResponse) error {
+			delegations = append(delegations, delegation)
+			return nil
+		})
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		if len(delegations) == 0 {
 			break
 		}
-		allDelegations = append(allDelegations, delegations...)
-
-		offset += limit
+		if err := onPage(delegations); err != nil {
+			return err
+		}
 
+		// a partial page is the last one
+		if len(delegations) < pageLimit {
+			break
+		}
+		lastId = delegations[len(delegations)-1].Id
 	}
 
-	return allDelegations, nil
+	return nil
 }
 
-func bulkInsertDelegations(dbInterface db.DBInterface, delegationsResponse []types.TzktDelegationsResponse) error {
+// highestLevel returns the highest block of the delegations
+func highestLevel(delegations []types.TzktDelegationsResponse) int32 {
+	var level int32
+	for _, delegation := range delegations {
+		level = max(level, delegation.Level)
+	}
+	return level
+}
+
+// bulkInsertDelegations stores the delegations and the checkpoint, if any, under writeContext and returns the delegations inserted
+func bulkInsertDelegations(ctx context.Context, dbInterface db.DBInterface, delegationsResponse []types.TzktDelegationsResponse, checkpoint *db.SyncState) ([]db.Delegations, error) {
 	delegations := make([]db.Delegations, len(delegationsResponse))
 	for i, delegation := range delegationsResponse {
 		delegations[i] = db.Delegations{
-			Delegator: delegation.Sender.Address,
-			Timestamp: delegation.Timestamp,
-			Block:     delegation.Level,
-			Amount:    delegation.Amount,
+			OperationID:  delegation.Id,
+			Hash:         delegation.Hash,
+			Delegator:    delegation.Sender.Address,
+			Timestamp:    delegation.Timestamp,
+			Block:        delegation.Level,
+			Amount:       delegation.Amount,
+			NewDelegate:  addressOf(delegation.NewDelegate),
+			PrevDelegate: addressOf(delegation.PrevDelegate),
 		}
 	}
-	err := dbInterface.BulkInsertDelegations(delegations)
-	if err != nil {
-		return err
+	writeCtx, cancel := writeContext(ctx)
+	defer cancel()
+	return dbInterface.BulkInsertDelegations(writeCtx, delegations, checkpoint)
+}
+
+// notify publishes the inserted delegations to the streams and dispatches them to the webhooks
+func (dw *DelegationsWatcher) notify(ctx context.Context, inserted []db.Delegations) {
+	if len(inserted) == 0 {
+		return
 	}
-	return nil
+	delegations := toDelegations(inserted)
+	if dw.broker != nil {
+		dw.broker.Publish(delegations)
+	}
+	if dw.dispatcher != nil {
+		dw.dispatcher.Dispatch(ctx, delegations)
+	}
+}
+
+// toDelegations converts the stored delegations to the delegations served by the api
+func toDelegations(stored []db.Delegations) []types.Delegation {
+	delegations := make([]types.Delegation, len(stored))
+	for i, delegation := range stored {
+		delegations[i] = types.Delegation{
+			Delegator:    delegation.Delegator,
+			Timestamp:    delegation.Timestamp,
+			Block:        delegation.Block,
+			Amount:       delegation.Amount,
+			NewDelegate:  delegation.NewDelegate,
+			PrevDelegate: delegation.PrevDelegate,
+		}
+	}
+	return delegations
+}
+
+// addressOf returns the address or an empty string when tzkt returned null
+func addressOf(address *types.Address) string {
+	if address == nil {
+		return ""
+	}
+	return address.Address
 }
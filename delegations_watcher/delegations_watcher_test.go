@@ -1,13 +1,19 @@
 package delegationswatcher
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	events "github.com/dipdup-net/go-lib/tzkt/events"
+	"github.com/ibraheemacara/tezos-delegation-service/broker"
 	"github.com/ibraheemacara/tezos-delegation-service/config"
 	"github.com/ibraheemacara/tezos-delegation-service/db"
 	"github.com/ibraheemacara/tezos-delegation-service/httpclient"
@@ -16,27 +22,82 @@ import (
 
 type MockDB struct {
 	db.DBInterface
-	LastBlock        int32
+	LastBlock int32
+	// SyncLevels, if set, is the level of each stream instead of LastBlock
+	SyncLevels       map[string]int32
 	BulkInsertCalled bool
 	BulkInsertDelegs []db.Delegations
+	BulkInsertCount  int
+	DeletedAbove     []int32
+	Checkpoints      []db.SyncState
+	Refreshes        chan struct{}
+	// Stored holds the operation ids already inserted, they are skipped like ON CONFLICT DO NOTHING does
+	Stored map[int64]bool
+	// StoredBlock is the highest block stored, Chunks the chunks of the parallel backfill
+	StoredBlock int32
+	Chunks      []db.BackfillChunk
 }
 
-func (m *MockDB) GetLastBlock() (int32, error) {
+func (m *MockDB) GetSyncLevel(ctx context.Context, stream string) (int32, error) {
+	if m.SyncLevels != nil {
+		return m.SyncLevels[stream], nil
+	}
 	return m.LastBlock, nil
 }
-func (m *MockDB) BulkInsertDelegations(delegations []db.Delegations) error {
+func (m *MockDB) GetLastBlock(ctx context.Context) (int32, error) {
+	return m.StoredBlock, nil
+}
+func (m *MockDB) GetBackfillChunks(ctx context.Context, afterLevel int32) ([]db.BackfillChunk, error) {
+	return m.Chunks, nil
+}
+func (m *MockDB) CreateBackfillChunks(ctx context.Context, chunks []db.BackfillChunk) error {
+	m.Chunks = chunks
+	return nil
+}
+func (m *MockDB) CompleteBackfillChunk(ctx context.Context, fromLevel int32) error {
+	return nil
+}
+func (m *MockDB) UpdateSyncLevel(ctx context.Context, stream string, level int32) error {
+	m.Checkpoints = append(m.Checkpoints, db.SyncState{Stream: stream, Level: level})
+	return nil
+}
+func (m *MockDB) BulkInsertDelegations(ctx context.Context, delegations []db.Delegations, checkpoint *db.SyncState) ([]db.Delegations, error) {
 	m.BulkInsertCalled = true
 	m.BulkInsertDelegs = delegations
+	if checkpoint != nil {
+		m.Checkpoints = append(m.Checkpoints, *checkpoint)
+	}
+	m.BulkInsertCount++
+	if m.Stored == nil {
+		m.Stored = make(map[int64]bool)
+	}
+	var inserted []db.Delegations
+	for _, delegation := range delegations {
+		if !m.Stored[delegation.OperationID] {
+			m.Stored[delegation.OperationID] = true
+			inserted = append(inserted, delegation)
+		}
+	}
+	return inserted, nil
+}
+func (m *MockDB) DeleteDelegationsAboveBlock(ctx context.Context, block int32) (int64, error) {
+	m.DeletedAbove = append(m.DeletedAbove, block)
+	return 0, nil
+}
+func (m *MockDB) RefreshLeaderboards(ctx context.Context) error {
+	m.Refreshes <- struct{}{}
 	return nil
 }
 
 type MockHTTPClient struct {
 	httpclient.HttpInterface
 	callCount int
+	urls      []string
 }
 
-func (m *MockHTTPClient) Get(url string) ([]byte, error) {
+func (m *MockHTTPClient) Get(ctx context.Context, url string) ([]byte, error) {
 	m.callCount++
+	m.urls = append(m.urls, url)
 	if m.callCount == 1 {
 		resp := []types.TzktDelegationsResponse{
 			{
@@ -51,36 +112,113 @@ func (m *MockHTTPClient) Get(url string) ([]byte, error) {
 	resp := []types.TzktDelegationsResponse{}
 	return json.Marshal(resp)
 }
+func (m *MockHTTPClient) Stream(ctx context.Context, url string, read func(io.Reader) error) error {
+	return streamGet(m.Get(ctx, url))(read)
+}
 
 type MockDBError struct {
-	GetLastBlockErr    error
+	GetSyncLevelErr    error
 	BulkInsertDelegErr error
 }
 
-func (m *MockDBError) GetLastBlock() (int32, error) {
-	return 0, m.GetLastBlockErr
+func (m *MockDBError) GetSyncLevel(context.Context, string) (int32, error) {
+	return 0, m.GetSyncLevelErr
 }
-func (m *MockDBError) BulkInsertDelegations([]db.Delegations) error {
-	return m.BulkInsertDelegErr
+func (m *MockDBError) GetLastBlock(context.Context) (int32, error)          { return 0, nil }
+func (m *MockDBError) UpdateSyncLevel(context.Context, string, int32) error { return nil }
+func (m *MockDBError) BulkInsertDelegations(context.Context, []db.Delegations, *db.SyncState) ([]db.Delegations, error) {
+	return nil, m.BulkInsertDelegErr
 }
 
 // Unused methods
-func (m *MockDBError) GetDelegations() ([]db.Delegations, error)               { return nil, nil }
-func (m *MockDBError) GetDelegationsByYear(string) ([]db.Delegations, error)   { return nil, nil }
-func (m *MockDBError) InsertDelegations(string, time.Time, int32, int64) error { return nil }
+func (m *MockDBError) GetDelegations(context.Context, db.DelegationsFilter, *db.Cursor, int) ([]db.Delegations, error) {
+	return nil, nil
+}
+func (m *MockDBError) ExportDelegations(context.Context, db.DelegationsFilter, func([]db.Delegations) error) error {
+	return nil
+}
+func (m *MockDBError) GetDelegationsByDelegator(context.Context, string, *db.Cursor, int) ([]db.Delegations, error) {
+	return nil, nil
+}
+func (m *MockDBError) GetDelegationsByBaker(context.Context, string, *db.Cursor, int) ([]db.Delegations, error) {
+	return nil, nil
+}
+func (m *MockDBError) InsertDelegations(context.Context, db.Delegations) error { return nil }
+func (m *MockDBError) DeleteDelegationsAboveBlock(context.Context, int32) (int64, error) {
+	return 0, nil
+}
+func (m *MockDBError) GetBackfillChunks(context.Context, int32) ([]db.BackfillChunk, error) {
+	return nil, nil
+}
+func (m *MockDBError) CreateBackfillChunks(context.Context, []db.BackfillChunk) error { return nil }
+func (m *MockDBError) CompleteBackfillChunk(context.Context, int32) error             { return nil }
+func (m *MockDBError) GetDelegatorHistory(context.Context, string) ([]db.Delegations, error) {
+	return nil, nil
+}
+func (m *MockDBError) GetBakerDelegators(context.Context, string, *db.Cursor, int) ([]db.Delegations, error) {
+	return nil, nil
+}
+func (m *MockDBError) GetBakerTotals(context.Context, string) (db.BakerTotals, error) {
+	return db.BakerTotals{}, nil
+}
+func (m *MockDBError) GetDelegationStats(context.Context, db.StatsQuery) ([]db.DelegationStats, error) {
+	return nil, nil
+}
+func (m *MockDBError) GetLeaderboard(context.Context, db.LeaderboardQuery) ([]db.LeaderboardEntry, error) {
+	return nil, nil
+}
+func (m *MockDBError) RefreshLeaderboards(context.Context) error         { return nil }
+func (m *MockDBError) GetWebhooks(context.Context) ([]db.Webhook, error) { return nil, nil }
+func (m *MockDBError) GetWebhook(context.Context, uint) (db.Webhook, error) {
+	return db.Webhook{}, db.ErrNotFound
+}
+func (m *MockDBError) CreateWebhook(context.Context, *db.Webhook) error { return nil }
+func (m *MockDBError) UpdateWebhook(context.Context, *db.Webhook) error { return nil }
+func (m *MockDBError) DeleteWebhook(context.Context, uint) error        { return nil }
+func (m *MockDBError) InsertWebhookDeadLetter(context.Context, db.WebhookDeadLetter) error {
+	return nil
+}
+func (m *MockDBError) GetWebhookDeadLetters(context.Context, uint) ([]db.WebhookDeadLetter, error) {
+	return nil, nil
+}
+func (m *MockDBError) Close() error { return nil }
 
 type MockTzkt struct {
 	msgChan chan events.Message
+	closed  bool
 }
 
 func (m *MockTzkt) Connect(ctx context.Context) error { return nil }
 func (m *MockTzkt) SubscribeToHead() error            { return nil }
 func (m *MockTzkt) Listen() <-chan events.Message     { return m.msgChan }
+func (m *MockTzkt) Close() error {
+	m.closed = true
+	return nil
+}
+
+// streamGet streams the body returned by a mock Get
+func streamGet(data []byte, err error) func(read func(io.Reader) error) error {
+	return func(read func(io.Reader) error) error {
+		if err != nil {
+			return err
+		}
+		return read(bytes.NewReader(data))
+	}
+}
+
+// collectPages returns a page handler appending every page to delegations
+func collectPages(delegations *[]types.TzktDelegationsResponse) func([]types.TzktDelegationsResponse) error {
+	return func(page []types.TzktDelegationsResponse) error {
+		*delegations = append(*delegations, page...)
+		return nil
+	}
+}
 
 func TestGetDelegations(t *testing.T) {
 	url := "http://fake-tzkt"
 	httpClient := &MockHTTPClient{}
-	delegations, err := getDelegations(url, 0, httpClient)
+	var delegations []types.TzktDelegationsResponse
+	err := getDelegations(context.Background(), url, 0, 0, httpClient, collectPages(&delegations))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -101,19 +239,74 @@ func TestGetDelegations(t *testing.T) {
 func TestGetDelegationsFromLevel(t *testing.T) {
 	url := "http://fake-tzkt"
 	httpClient := &MockHTTPClient{}
-	delegations, err := getDelegationsFromLevel(url, 0, httpClient)
+	var delegations []types.TzktDelegationsResponse
+	err := getDelegations(context.Background(), url, 5, 0, httpClient, collectPages(&delegations))
 	if err != nil {
 		t.Fatal(err)
 	}
 	if len(delegations) != 1 {
 		t.Errorf("expected 1 delegation, got %d", len(delegations))
 	}
+	expectedUrl := "http://fake-tzkt/v1/operations/delegations?limit=10000&sort.asc=id&id.gt=0&level.gt=5"
+	if len(httpClient.urls) != 1 || httpClient.urls[0] != expectedUrl {
+		t.Errorf("expected a single call to %s, got %v", expectedUrl, httpClient.urls)
+	}
+}
+
+type MockPagesHTTPClient struct {
+	httpclient.HttpInterface
+	pages [][]types.TzktDelegationsResponse
+	urls  []string
+}
+
+func (m *MockPagesHTTPClient) Get(ctx context.Context, url string) ([]byte, error) {
+	m.urls = append(m.urls, url)
+	if len(m.urls) > len(m.pages) {
+		return json.Marshal([]types.TzktDelegationsResponse{})
+	}
+	return json.Marshal(m.pages[len(m.urls)-1])
+}
+func (m *MockPagesHTTPClient) Stream(ctx context.Context, url string, read func(io.Reader) error) error {
+	return streamGet(m.Get(ctx, url))(read)
+}
+
+func TestGetDelegationsIdPaging(t *testing.T) {
+	fullPage := make([]types.TzktDelegationsResponse, pageLimit)
+	for i := range fullPage {
+		fullPage[i].Id = int64(i + 1)
+	}
+	httpClient := &MockPagesHTTPClient{
+		pages: [][]types.TzktDelegationsResponse{fullPage, {{Id: pageLimit + 1}}},
+	}
+
+	var delegations []types.TzktDelegationsResponse
+	err := getDelegations(context.Background(), "http://fake-tzkt", 5, 20, httpClient, collectPages(&delegations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delegations) != pageLimit+1 {
+		t.Errorf("expected %d delegations, got %d", pageLimit+1, len(delegations))
+	}
+	// the partial second page is the last one
+	expectedUrls := []string{
+		"http://fake-tzkt/v1/operations/delegations?limit=10000&sort.asc=id&id.gt=0&level.gt=5&level.le=20",
+		"http://fake-tzkt/v1/operations/delegations?limit=10000&sort.asc=id&id.gt=10000&level.gt=5&level.le=20",
+	}
+	if len(httpClient.urls) != len(expectedUrls) {
+		t.Fatalf("expected urls %v, got %v", expectedUrls, httpClient.urls)
+	}
+	for i := range expectedUrls {
+		if httpClient.urls[i] != expectedUrls[i] {
+			t.Errorf("expected url %s, got %s", expectedUrls[i], httpClient.urls[i])
+		}
+	}
 }
 
 // we set offset to 1 so first call returns empty
 func TestGetDelegations_Empty(t *testing.T) {
 	httpClient := &MockHTTPClient{callCount: 1}
-	delegations, err := getDelegations("http://fake-tzkt", 0, httpClient)
+	var delegations []types.TzktDelegationsResponse
+	err := getDelegations(context.Background(), "http://fake-tzkt", 0, 0, httpClient, collectPages(&delegations))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -122,15 +315,93 @@ func TestGetDelegations_Empty(t *testing.T) {
 	}
 }
 
+func TestGetDelegationsPageError(t *testing.T) {
+	httpClient := &MockHTTPClient{}
+	err := getDelegations(context.Background(), "http://fake-tzkt", 0, 0, httpClient, func([]types.TzktDelegationsResponse) error {
+		return fmt.Errorf("insert error")
+	})
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	// no other page is fetched once a page fails
+	if httpClient.callCount != 1 {
+		t.Errorf("expected 1 call, got %d", httpClient.callCount)
+	}
+}
+
+func TestGetDelegationsCancelled(t *testing.T) {
+	httpClient := &MockHTTPClient{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var delegations []types.TzktDelegationsResponse
+	err := getDelegations(ctx, "http://fake-tzkt", 0, 0, httpClient, collectPages(&delegations))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled, got %v", err)
+	}
+	if httpClient.callCount != 0 {
+		t.Errorf("expected no call once cancelled, got %d", httpClient.callCount)
+	}
+}
+
+func TestGetDelegationsAbortsRequest(t *testing.T) {
+	// tzkt never answers
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	httpClient := httpclient.NewHttpClient(time.Minute, httpclient.RetryPolicy{}, httpclient.RateLimit{}, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	var delegations []types.TzktDelegationsResponse
+	err := getDelegations(ctx, server.URL, 0, 0, httpClient, collectPages(&delegations))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("expected the request to be aborted, took %v", time.Since(start))
+	}
+}
+
 func TestBulkInsertDelegations(t *testing.T) {
-	err := bulkInsertDelegations(&MockDB{}, []types.TzktDelegationsResponse{})
+	_, err := bulkInsertDelegations(context.Background(), &MockDB{}, []types.TzktDelegationsResponse{}, nil)
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
 }
 
+func TestBulkInsertDelegationsFields(t *testing.T) {
+	mockDB := &MockDB{}
+	_, err := bulkInsertDelegations(context.Background(), mockDB, []types.TzktDelegationsResponse{
+		{
+			Id:           42,
+			Hash:         "ooHash",
+			Sender:       types.Address{Address: "tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd"},
+			Level:        1,
+			NewDelegate:  &types.Address{Address: "tz3WXYtyDUNL91qfiCJtVUX746QpNv5i5ve5"},
+			PrevDelegate: nil,
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mockDB.BulkInsertDelegs) != 1 {
+		t.Fatalf("expected 1 delegation, got %d", len(mockDB.BulkInsertDelegs))
+	}
+	if mockDB.BulkInsertDelegs[0].OperationID != 42 || mockDB.BulkInsertDelegs[0].Hash != "ooHash" {
+		t.Errorf("expected operation 42 with hash ooHash, got %d %s", mockDB.BulkInsertDelegs[0].OperationID, mockDB.BulkInsertDelegs[0].Hash)
+	}
+	if mockDB.BulkInsertDelegs[0].NewDelegate != "tz3WXYtyDUNL91qfiCJtVUX746QpNv5i5ve5" {
+		t.Errorf("expected new delegate tz3WXYtyDUNL91qfiCJtVUX746QpNv5i5ve5, got %s", mockDB.BulkInsertDelegs[0].NewDelegate)
+	}
+	if mockDB.BulkInsertDelegs[0].PrevDelegate != "" {
+		t.Errorf("expected no previous delegate, got %s", mockDB.BulkInsertDelegs[0].PrevDelegate)
+	}
+}
+
 func TestBulkInsertDelegationsError(t *testing.T) {
-	err := bulkInsertDelegations(&MockDBError{BulkInsertDelegErr: fmt.Errorf("bulk insert error")}, []types.TzktDelegationsResponse{})
+	_, err := bulkInsertDelegations(context.Background(), &MockDBError{BulkInsertDelegErr: fmt.Errorf("bulk insert error")}, []types.TzktDelegationsResponse{}, nil)
 	if err == nil {
 		t.Errorf("expected error, got nil")
 	}
@@ -144,10 +415,10 @@ func TestWatchBlocksDelegationsInserted(t *testing.T) {
 	cfg.Tzkt.Url = "http://fake-tzkt"
 
 	watcher := &DelegationsWatcher{
-		config:     cfg,
-		httpClient: &MockHTTPClient{},
-		db:         mockDB,
-		tzktClient: mockTzkt,
+		config: cfg,
+		source: newTzktSource(cfg.Tzkt.Url, &MockHTTPClient{}),
+		db:     mockDB,
+		heads:  mockTzkt,
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -168,12 +439,135 @@ func TestWatchBlocksDelegationsInserted(t *testing.T) {
 	cancel()
 	<-done
 
-	fmt.Println("BulkInsertCalled:", mockDB.BulkInsertCalled)
 	if !mockDB.BulkInsertCalled {
 		t.Errorf("expected BulkInsertDelegations to be called")
 	}
 }
 
+func TestProcessHeadPublishes(t *testing.T) {
+	delegationsBroker := broker.NewBroker()
+	subscription := delegationsBroker.Subscribe(broker.Filter{})
+	watcher := &DelegationsWatcher{
+		source: &MockSource{failAfter: -1},
+		db:     &MockDB{},
+		broker: delegationsBroker,
+	}
+
+	if err := watcher.processHead(context.Background(), newBlockTracker(9), 11, "BLa"); err != nil {
+		t.Fatal(err)
+	}
+	if len(subscription.Events()) != 2 {
+		t.Fatalf("expected 2 delegations published, got %d", len(subscription.Events()))
+	}
+	if delegation := <-subscription.Events(); delegation.Block != 10 {
+		t.Errorf("expected the delegation of block 10 first, got %+v", delegation)
+	}
+
+	// nothing is published when the insert fails
+	watcher.db = &MockDBError{BulkInsertDelegErr: errors.New("insert failed")}
+	if err := watcher.processHead(context.Background(), newBlockTracker(11), 12, "BLb"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if len(subscription.Events()) != 1 {
+		t.Errorf("expected no new delegation published, got %d", len(subscription.Events())-1)
+	}
+}
+
+func TestProcessHeadStoresPages(t *testing.T) {
+	delegationsBroker := broker.NewBroker()
+	subscription := delegationsBroker.Subscribe(broker.Filter{})
+	mockDB := &MockDB{}
+	// catching up blocks 10 to 14, the source fails after two pages
+	watcher := &DelegationsWatcher{
+		source: &MockSource{failAfter: 2},
+		db:     mockDB,
+		broker: delegationsBroker,
+	}
+
+	if err := watcher.processHead(context.Background(), newBlockTracker(9), 14, "BLa"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	// the pages received before the failure are stored, checkpointed and published
+	expected := []db.SyncState{{Stream: db.SyncStreamLive, Level: 10}, {Stream: db.SyncStreamLive, Level: 11}}
+	if mockDB.BulkInsertCount != 2 || len(mockDB.Checkpoints) != len(expected) {
+		t.Fatalf("expected 2 pages stored with checkpoints %v, got %d pages and %v", expected, mockDB.BulkInsertCount, mockDB.Checkpoints)
+	}
+	for i := range expected {
+		if mockDB.Checkpoints[i] != expected[i] {
+			t.Errorf("expected checkpoint %v, got %v", expected[i], mockDB.Checkpoints[i])
+		}
+	}
+	if len(subscription.Events()) != 2 {
+		t.Errorf("expected 2 delegations published, got %d", len(subscription.Events()))
+	}
+}
+
+func TestProcessHeadReplayPublishesNew(t *testing.T) {
+	delegationsBroker := broker.NewBroker()
+	subscription := delegationsBroker.Subscribe(broker.Filter{})
+	// the delegation of block 10 is already stored, e.g. the head is replayed after a restart
+	watcher := &DelegationsWatcher{
+		source: &MockSource{failAfter: -1},
+		db:     &MockDB{Stored: map[int64]bool{10: true}},
+		broker: delegationsBroker,
+	}
+
+	if err := watcher.processHead(context.Background(), newBlockTracker(9), 11, "BLa"); err != nil {
+		t.Fatal(err)
+	}
+	if len(subscription.Events()) != 1 {
+		t.Fatalf("expected only the new delegation published, got %d", len(subscription.Events()))
+	}
+	if delegation := <-subscription.Events(); delegation.Block != 11 {
+		t.Errorf("expected the delegation of block 11, got %+v", delegation)
+	}
+
+	// replaying the same head again publishes nothing
+	if err := watcher.processHead(context.Background(), newBlockTracker(9), 11, "BLa"); err != nil {
+		t.Fatal(err)
+	}
+	if len(subscription.Events()) != 0 {
+		t.Errorf("expected no delegation published, got %d", len(subscription.Events()))
+	}
+}
+
+func TestRefreshLeaderboards(t *testing.T) {
+	mockDB := &MockDB{Refreshes: make(chan struct{})}
+	watcher := &DelegationsWatcher{
+		source:    &MockSource{failAfter: -1},
+		db:        mockDB,
+		refreshes: make(chan struct{}, 1),
+	}
+
+	// the requests sent before the refresher runs are merged into a single refresh
+	blocks := newBlockTracker(9)
+	for level := int32(10); level <= 12; level++ {
+		if err := watcher.processHead(context.Background(), blocks, level, fmt.Sprintf("BL%d", level)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watcher.refreshLeaderboards(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-mockDB.Refreshes:
+	case <-time.After(time.Second):
+		t.Fatal("expected the leaderboards to be refreshed")
+	}
+	select {
+	case <-mockDB.Refreshes:
+		t.Error("expected a single refresh")
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel()
+	<-done
+}
+
 func TestWatchBlocksNoDelegations(t *testing.T) {
 	msgChan := make(chan events.Message, 1)
 	mockTzkt := &MockTzkt{msgChan: msgChan}
@@ -184,10 +578,10 @@ func TestWatchBlocksNoDelegations(t *testing.T) {
 	httpClient := &MockHTTPClient{callCount: 1}
 
 	watcher := &DelegationsWatcher{
-		config:     cfg,
-		httpClient: httpClient,
-		db:         mockDB,
-		tzktClient: mockTzkt,
+		config: cfg,
+		source: newTzktSource(cfg.Tzkt.Url, httpClient),
+		db:     mockDB,
+		heads:  mockTzkt,
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -216,10 +610,10 @@ func TestWatchBlocksDBInsertError(t *testing.T) {
 	cfg.Tzkt.Url = "http://fake-tzkt"
 
 	watcher := &DelegationsWatcher{
-		config:     cfg,
-		httpClient: &MockHTTPClient{},
-		db:         mockDB,
-		tzktClient: mockTzkt,
+		config: cfg,
+		source: newTzktSource(cfg.Tzkt.Url, &MockHTTPClient{}),
+		db:     mockDB,
+		heads:  mockTzkt,
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -236,3 +630,156 @@ func TestWatchBlocksDBInsertError(t *testing.T) {
 	// Should not panic even if DB insert fails
 	watcher.WatchNewBlocks(ctx)
 }
+
+func TestWatchBlocksReorg(t *testing.T) {
+	msgChan := make(chan events.Message, 4)
+	mockTzkt := &MockTzkt{msgChan: msgChan}
+	mockDB := &MockDB{}
+	httpClient := &MockHTTPClient{callCount: 1}
+	cfg := config.Config{}
+	cfg.Tzkt.Url = "http://fake-tzkt"
+
+	watcher := &DelegationsWatcher{
+		config: cfg,
+		source: newTzktSource(cfg.Tzkt.Url, httpClient),
+		db:     mockDB,
+		heads:  mockTzkt,
+	}
+
+	msgChan <- events.Message{Channel: events.ChannelHead, Body: map[string]interface{}{"level": float64(10), "hash": "BLa"}}
+	msgChan <- events.Message{Channel: events.ChannelHead, Body: map[string]interface{}{"level": float64(11), "hash": "BLb"}}
+	msgChan <- events.Message{Channel: events.ChannelHead, Type: events.MessageTypeReorg, State: 10}
+	msgChan <- events.Message{Channel: events.ChannelHead, Body: map[string]interface{}{"level": float64(12), "hash": "BLc"}}
+	close(msgChan)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watcher.WatchNewBlocks(ctx)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	if len(mockDB.DeletedAbove) != 1 || mockDB.DeletedAbove[0] != 10 {
+		t.Errorf("expected delegations above block 10 to be deleted, got %v", mockDB.DeletedAbove)
+	}
+	expectedUrl := "http://fake-tzkt/v1/operations/delegations?limit=10000&sort.asc=id&id.gt=0&level.gt=10&level.le=12"
+	if httpClient.urls[len(httpClient.urls)-1] != expectedUrl {
+		t.Errorf("expected blocks after the rollback to be fetched again with %s, got %s", expectedUrl, httpClient.urls[len(httpClient.urls)-1])
+	}
+}
+
+func TestWatchBlocksReplacedHead(t *testing.T) {
+	msgChan := make(chan events.Message, 3)
+	mockTzkt := &MockTzkt{msgChan: msgChan}
+	mockDB := &MockDB{}
+	httpClient := &MockHTTPClient{callCount: 1}
+	cfg := config.Config{}
+	cfg.Tzkt.Url = "http://fake-tzkt"
+
+	watcher := &DelegationsWatcher{
+		config: cfg,
+		source: newTzktSource(cfg.Tzkt.Url, httpClient),
+		db:     mockDB,
+		heads:  mockTzkt,
+	}
+
+	msgChan <- events.Message{Channel: events.ChannelHead, Body: map[string]interface{}{"level": float64(10), "hash": "BLa"}}
+	msgChan <- events.Message{Channel: events.ChannelHead, Body: map[string]interface{}{"level": float64(10), "hash": "BLa"}}
+	msgChan <- events.Message{Channel: events.ChannelHead, Body: map[string]interface{}{"level": float64(10), "hash": "BLb"}}
+	close(msgChan)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watcher.WatchNewBlocks(ctx)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	if len(mockDB.DeletedAbove) != 1 || mockDB.DeletedAbove[0] != 9 {
+		t.Errorf("expected delegations above block 9 to be deleted, got %v", mockDB.DeletedAbove)
+	}
+	// the same head received twice is fetched only once
+	if len(httpClient.urls) != 2 {
+		t.Errorf("expected 2 fetches, got %d", len(httpClient.urls))
+	}
+}
+
+func TestWatchBlocksSyncLevel(t *testing.T) {
+	msgChan := make(chan events.Message, 2)
+	mockTzkt := &MockTzkt{msgChan: msgChan}
+	mockDB := &MockDB{LastBlock: 8}
+	httpClient := &MockHTTPClient{}
+	cfg := config.Config{}
+	cfg.Tzkt.Url = "http://fake-tzkt"
+
+	watcher := &DelegationsWatcher{
+		config: cfg,
+		source: newTzktSource(cfg.Tzkt.Url, httpClient),
+		db:     mockDB,
+		heads:  mockTzkt,
+	}
+
+	msgChan <- events.Message{Channel: events.ChannelHead, Body: map[string]interface{}{"level": float64(10), "hash": "BLa"}}
+	msgChan <- events.Message{Channel: events.ChannelHead, Body: map[string]interface{}{"level": float64(11), "hash": "BLb"}}
+	close(msgChan)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watcher.WatchNewBlocks(ctx)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	// blocks are fetched from the last synced block
+	expectedUrl := "http://fake-tzkt/v1/operations/delegations?limit=10000&sort.asc=id&id.gt=0&level.gt=8&level.le=10"
+	if httpClient.urls[0] != expectedUrl {
+		t.Errorf("expected first fetch %s, got %s", expectedUrl, httpClient.urls[0])
+	}
+	// the page moves the checkpoint to the block of its delegation, then the head blocks are checkpointed
+	expected := []db.SyncState{{Stream: db.SyncStreamLive, Level: 1}, {Stream: db.SyncStreamLive, Level: 10}, {Stream: db.SyncStreamLive, Level: 11}}
+	if len(mockDB.Checkpoints) != len(expected) {
+		t.Fatalf("expected checkpoints %v, got %v", expected, mockDB.Checkpoints)
+	}
+	for i := range expected {
+		if mockDB.Checkpoints[i] != expected[i] {
+			t.Errorf("expected checkpoint %v, got %v", expected[i], mockDB.Checkpoints[i])
+		}
+	}
+}
+
+func TestWatchBlocksShutdown(t *testing.T) {
+	// the channel stays open, only the cancellation stops the watcher
+	mockTzkt := &MockTzkt{msgChan: make(chan events.Message)}
+	watcher := &DelegationsWatcher{
+		source: newTzktSource("http://fake-tzkt", &MockHTTPClient{}),
+		db:     &MockDB{},
+		heads:  mockTzkt,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watcher.WatchNewBlocks(ctx)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watcher did not stop after the context was cancelled")
+	}
+	if !mockTzkt.closed {
+		t.Errorf("expected tzkt connection to be closed")
+	}
+}
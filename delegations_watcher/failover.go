@@ -0,0 +1,150 @@
+package delegationswatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	events "github.com/dipdup-net/go-lib/tzkt/events"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// failoverSource reads from one source at a time and moves to the next one when a request fails.
+// The sources must agree on the operation ids, which is the case of tzkt instances indexing the same chain.
+type failoverSource struct {
+	mu      sync.Mutex
+	names   []string
+	sources []Source
+	active  int
+}
+
+func newFailoverSource(names []string, sources []Source) *failoverSource {
+	return &failoverSource{names: names, sources: sources}
+}
+
+// current returns the source in use and its index
+func (f *failoverSource) current() (int, Source) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active, f.sources[f.active]
+}
+
+// failed moves to the next source, unless another request already moved away from the failed one
+func (f *failoverSource) failed(index int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.active != index {
+		return
+	}
+	f.active = (index + 1) % len(f.sources)
+	log.Warnf("Source %s failed: %v, switching to %s", f.names[index], err, f.names[f.active])
+}
+
+func (f *failoverSource) HeadLevel(ctx context.Context) (int32, error) {
+	var errs []error
+	for range f.sources {
+		index, source := f.current()
+		level, err := source.HeadLevel(ctx)
+		if err == nil {
+			return level, nil
+		}
+		if ctx.Err() != nil {
+			return 0, err
+		}
+		errs = append(errs, err)
+		f.failed(index, err)
+	}
+	return 0, fmt.Errorf("all sources failed: %w", errors.Join(errs...))
+}
+
+// Delegations resumes with the next source after the last complete block delivered when a source fails,
+// errors returned by onPage are returned as is
+func (f *failoverSource) Delegations(ctx context.Context, fromLevel, toLevel int32, onPage func([]types.TzktDelegationsResponse) error) error {
+	var errs []error
+	for range f.sources {
+		index, source := f.current()
+		var pageErr error
+		err := source.Delegations(ctx, fromLevel, toLevel, func(delegations []types.TzktDelegationsResponse) error {
+			if err := onPage(delegations); err != nil {
+				pageErr = err
+				return err
+			}
+			fromLevel = max(fromLevel, lastCompleteLevel(delegations))
+			return nil
+		})
+		if err == nil {
+			return nil
+		}
+		if pageErr != nil || ctx.Err() != nil {
+			return err
+		}
+		errs = append(errs, err)
+		f.failed(index, err)
+	}
+	return fmt.Errorf("all sources failed: %w", errors.Join(errs...))
+}
+
+// alternate returns a source reading from the source after the one in use, to cross-check its data
+func (f *failoverSource) alternate() Source {
+	return &alternateSource{failover: f}
+}
+
+type alternateSource struct {
+	failover *failoverSource
+}
+
+func (a *alternateSource) source() Source {
+	index, _ := a.failover.current()
+	return a.failover.sources[(index+1)%len(a.failover.sources)]
+}
+
+func (a *alternateSource) HeadLevel(ctx context.Context) (int32, error) {
+	return a.source().HeadLevel(ctx)
+}
+
+func (a *alternateSource) Delegations(ctx context.Context, fromLevel, toLevel int32, onPage func([]types.TzktDelegationsResponse) error) error {
+	return a.source().Delegations(ctx, fromLevel, toLevel, onPage)
+}
+
+// failoverHeads connects to the first heads stream available, starting with the one used last
+type failoverHeads struct {
+	names   []string
+	clients []HeadsClient
+	active  int
+}
+
+func newFailoverHeads(names []string, clients []HeadsClient) *failoverHeads {
+	return &failoverHeads{names: names, clients: clients}
+}
+
+func (f *failoverHeads) Connect(ctx context.Context) error {
+	var errs []error
+	for range f.clients {
+		err := f.clients[f.active].Connect(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		errs = append(errs, err)
+		next := (f.active + 1) % len(f.clients)
+		log.Warnf("Heads stream %s failed: %v, switching to %s", f.names[f.active], err, f.names[next])
+		f.active = next
+	}
+	return errors.Join(errs...)
+}
+
+func (f *failoverHeads) SubscribeToHead() error {
+	return f.clients[f.active].SubscribeToHead()
+}
+
+func (f *failoverHeads) Listen() <-chan events.Message {
+	return f.clients[f.active].Listen()
+}
+
+func (f *failoverHeads) Close() error {
+	return f.clients[f.active].Close()
+}
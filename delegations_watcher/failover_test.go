@@ -0,0 +1,138 @@
+package delegationswatcher
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	events "github.com/dipdup-net/go-lib/tzkt/events"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+// MockSource delivers a page per block in ]fromLevel, toLevel], up to head when toLevel is 0, and fails after failAfter
+// pages when it is set
+type MockSource struct {
+	head       int32
+	failAfter  int
+	fromLevels []int32
+}
+
+func (m *MockSource) HeadLevel(ctx context.Context) (int32, error) {
+	if m.failAfter == 0 && m.head == 0 {
+		return 0, fmt.Errorf("source down")
+	}
+	return m.head, nil
+}
+
+func (m *MockSource) Delegations(ctx context.Context, fromLevel, toLevel int32, onPage func([]types.TzktDelegationsResponse) error) error {
+	m.fromLevels = append(m.fromLevels, fromLevel)
+	if toLevel == 0 {
+		toLevel = m.head
+	}
+	pages := 0
+	for level := fromLevel + 1; level <= toLevel; level++ {
+		if m.failAfter >= 0 && pages == m.failAfter {
+			return fmt.Errorf("source down")
+		}
+		if err := onPage([]types.TzktDelegationsResponse{{Id: int64(level), Level: level}}); err != nil {
+			return err
+		}
+		pages++
+	}
+	return nil
+}
+
+func TestFailoverSourceDelegations(t *testing.T) {
+	first := &MockSource{failAfter: 2}
+	second := &MockSource{failAfter: -1}
+	source := newFailoverSource([]string{"first", "second"}, []Source{first, second})
+
+	var delegations []types.TzktDelegationsResponse
+	if err := source.Delegations(context.Background(), 0, 5, collectPages(&delegations)); err != nil {
+		t.Fatal(err)
+	}
+	if len(delegations) != 5 {
+		t.Errorf("expected 5 delegations, got %d", len(delegations))
+	}
+	// the second source resumes after the blocks delivered by the first one
+	if len(second.fromLevels) != 1 || second.fromLevels[0] != 2 {
+		t.Errorf("expected second source to start after block 2, got %v", second.fromLevels)
+	}
+
+	// the second source stays in use
+	if err := source.Delegations(context.Background(), 5, 6, collectPages(&delegations)); err != nil {
+		t.Fatal(err)
+	}
+	if len(first.fromLevels) != 1 {
+		t.Errorf("expected first source to be called once, got %v", first.fromLevels)
+	}
+}
+
+func TestFailoverSourceAllFailed(t *testing.T) {
+	source := newFailoverSource([]string{"first", "second"}, []Source{&MockSource{}, &MockSource{}})
+
+	var delegations []types.TzktDelegationsResponse
+	if err := source.Delegations(context.Background(), 0, 5, collectPages(&delegations)); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if _, err := source.HeadLevel(context.Background()); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestFailoverSourcePageError(t *testing.T) {
+	first := &MockSource{failAfter: -1}
+	second := &MockSource{failAfter: -1}
+	source := newFailoverSource([]string{"first", "second"}, []Source{first, second})
+
+	err := source.Delegations(context.Background(), 0, 5, func([]types.TzktDelegationsResponse) error {
+		return fmt.Errorf("insert error")
+	})
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	// a storage error is not a failure of the source
+	if len(second.fromLevels) != 0 {
+		t.Errorf("expected no failover, got %v", second.fromLevels)
+	}
+}
+
+func TestFailoverSourceHeadLevel(t *testing.T) {
+	source := newFailoverSource([]string{"first", "second"}, []Source{&MockSource{}, &MockSource{head: 42}})
+
+	level, err := source.HeadLevel(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level != 42 {
+		t.Errorf("expected head level 42, got %d", level)
+	}
+	// the alternate source is the one not in use
+	if level, _ := source.alternate().HeadLevel(context.Background()); level != 0 {
+		t.Errorf("expected alternate source to be the first one, got head %d", level)
+	}
+}
+
+// MockHeadsError fails to connect
+type MockHeadsError struct {
+	MockTzkt
+}
+
+func (m *MockHeadsError) Connect(ctx context.Context) error { return fmt.Errorf("connection refused") }
+
+func TestFailoverHeads(t *testing.T) {
+	msgChan := make(chan events.Message)
+	heads := newFailoverHeads([]string{"first", "second"}, []HeadsClient{&MockHeadsError{}, &MockTzkt{msgChan: msgChan}})
+
+	if err := heads.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if heads.Listen() != (<-chan events.Message)(msgChan) {
+		t.Errorf("expected to listen to the second heads stream")
+	}
+
+	heads = newFailoverHeads([]string{"first"}, []HeadsClient{&MockHeadsError{}})
+	if err := heads.Connect(context.Background()); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
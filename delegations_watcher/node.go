@@ -0,0 +1,301 @@
+package delegationswatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	events "github.com/dipdup-net/go-lib/tzkt/events"
+	"github.com/ibraheemacara/tezos-delegation-service/httpclient"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// nodeSource reads the delegations from the RPC of a tezos node. The delegated amount and the previous delegate
+// are read from the context of the blocks, backfilling old blocks needs an archive node.
+type nodeSource struct {
+	url        string
+	httpClient httpclient.HttpInterface
+}
+
+func newNodeSource(url string, httpClient httpclient.HttpInterface) *nodeSource {
+	return &nodeSource{url: url, httpClient: httpClient}
+}
+
+type nodeBlockHeader struct {
+	Level       int32     `json:"level"`
+	Hash        string    `json:"hash"`
+	Predecessor string    `json:"predecessor"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+type nodeOperation struct {
+	Hash     string                 `json:"hash"`
+	Contents []nodeOperationContent `json:"contents"`
+}
+
+type nodeOperationContent struct {
+	Kind     string `json:"kind"`
+	Source   string `json:"source"`
+	Delegate string `json:"delegate"`
+	Metadata struct {
+		OperationResult          nodeOperationResult     `json:"operation_result"`
+		InternalOperationResults []nodeInternalOperation `json:"internal_operation_results"`
+	} `json:"metadata"`
+}
+
+// nodeInternalOperation is an operation emitted by a smart contract
+type nodeInternalOperation struct {
+	Kind     string              `json:"kind"`
+	Source   string              `json:"source"`
+	Delegate string              `json:"delegate"`
+	Result   nodeOperationResult `json:"result"`
+}
+
+type nodeOperationResult struct {
+	Status string `json:"status"`
+}
+
+// managerOperationsPass is the validation pass of the operations list holding the delegations
+const managerOperationsPass = 3
+
+func (s *nodeSource) HeadLevel(ctx context.Context) (int32, error) {
+	header, err := s.header(ctx, "head")
+	if err != nil {
+		return 0, err
+	}
+	return header.Level, nil
+}
+
+// Delegations reads the blocks one after the other, every block with delegations is a page
+func (s *nodeSource) Delegations(ctx context.Context, fromLevel, toLevel int32, onPage func([]types.TzktDelegationsResponse) error) error {
+	if toLevel == 0 {
+		headLevel, err := s.HeadLevel(ctx)
+		if err != nil {
+			return err
+		}
+		toLevel = headLevel
+	}
+
+	for level := fromLevel + 1; level <= toLevel; level++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		delegations, err := s.blockDelegations(ctx, level)
+		if err != nil {
+			return fmt.Errorf("failed to read block %v: %w", level, err)
+		}
+		if len(delegations) == 0 {
+			continue
+		}
+		if err := onPage(delegations); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockDelegations extracts the applied delegations of the block, including the ones emitted by smart contracts
+func (s *nodeSource) blockDelegations(ctx context.Context, level int32) ([]types.TzktDelegationsResponse, error) {
+	var passes [][]nodeOperation
+	if err := s.get(ctx, fmt.Sprintf("/chains/main/blocks/%d/operations", level), &passes); err != nil {
+		return nil, err
+	}
+	if len(passes) <= managerOperationsPass {
+		return nil, nil
+	}
+
+	var delegations []types.TzktDelegationsResponse
+	appendDelegation := func(hash, source, delegate string) {
+		delegation := types.TzktDelegationsResponse{
+			Id:     nodeOperationId(level, len(delegations)),
+			Hash:   hash,
+			Level:  level,
+			Sender: types.Address{Address: source},
+		}
+		if delegate != "" {
+			delegation.NewDelegate = &types.Address{Address: delegate}
+		}
+		delegations = append(delegations, delegation)
+	}
+	for _, operation := range passes[managerOperationsPass] {
+		for _, content := range operation.Contents {
+			if content.Kind == "delegation" && content.Metadata.OperationResult.Status == "applied" {
+				appendDelegation(operation.Hash, content.Source, content.Delegate)
+			}
+			for _, internal := range content.Metadata.InternalOperationResults {
+				if internal.Kind == "delegation" && internal.Result.Status == "applied" {
+					appendDelegation(operation.Hash, internal.Source, internal.Delegate)
+				}
+			}
+		}
+	}
+	if len(delegations) == 0 {
+		return nil, nil
+	}
+
+	header, err := s.header(ctx, strconv.Itoa(int(level)))
+	if err != nil {
+		return nil, err
+	}
+	for i := range delegations {
+		delegations[i].Timestamp = header.Timestamp
+		if err := s.readContext(ctx, &delegations[i]); err != nil {
+			return nil, err
+		}
+	}
+	return delegations, nil
+}
+
+// readContext sets the balance of the delegator after the block and its delegate before the block
+func (s *nodeSource) readContext(ctx context.Context, delegation *types.TzktDelegationsResponse) error {
+	address := delegation.Sender.Address
+
+	var balance string
+	if err := s.get(ctx, fmt.Sprintf("/chains/main/blocks/%d/context/contracts/%s/balance", delegation.Level, address), &balance); err != nil {
+		return err
+	}
+	amount, err := strconv.ParseInt(balance, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid balance %q for %s: %w", balance, address, err)
+	}
+	delegation.Amount = amount
+
+	// the node answers 404 for a contract without delegate or that did not exist yet
+	var prevDelegate string
+	err = s.get(ctx, fmt.Sprintf("/chains/main/blocks/%d/context/contracts/%s/delegate", delegation.Level-1, address), &prevDelegate)
+	if err != nil && !errors.Is(err, httpclient.ErrNotFound) {
+		return err
+	}
+	if prevDelegate != "" {
+		delegation.PrevDelegate = &types.Address{Address: prevDelegate}
+	}
+	return nil
+}
+
+// nodeOperationId builds a stable id for the index-th delegation of a block, blocks hold far less than 65536 delegations.
+// The ids don't match the tzkt operation ids, the database records which source filled it and refuses the other one.
+func nodeOperationId(level int32, index int) int64 {
+	return int64(level)<<16 | int64(index)
+}
+
+func (s *nodeSource) header(ctx context.Context, block string) (nodeBlockHeader, error) {
+	var header nodeBlockHeader
+	err := s.get(ctx, fmt.Sprintf("/chains/main/blocks/%s/header", block), &header)
+	return header, err
+}
+
+func (s *nodeSource) get(ctx context.Context, path string, value any) error {
+	return httpclient.DecodeJSON(ctx, s.httpClient, s.url+path, value)
+}
+
+// nodeHeads polls the head of a node and sends a head message every time it changes. The node has no
+// notification of reorganizations, they are detected from the hashes of the previous heads.
+type nodeHeads struct {
+	source   *nodeSource
+	interval time.Duration
+	messages chan events.Message
+	cancel   context.CancelFunc
+}
+
+func newNodeHeads(source *nodeSource, interval time.Duration) *nodeHeads {
+	return &nodeHeads{source: source, interval: interval}
+}
+
+func (h *nodeHeads) Connect(ctx context.Context) error {
+	if _, err := h.source.header(ctx, "head"); err != nil {
+		return err
+	}
+	pollCtx, cancel := context.WithCancel(ctx)
+	h.cancel = cancel
+	h.messages = make(chan events.Message)
+	go h.poll(pollCtx, h.messages)
+	return nil
+}
+
+func (h *nodeHeads) SubscribeToHead() error {
+	return nil
+}
+
+func (h *nodeHeads) Listen() <-chan events.Message {
+	return h.messages
+}
+
+func (h *nodeHeads) Close() error {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	return nil
+}
+
+// poll sends the new heads until ctx is cancelled or the node can't be reached, messages is closed when it returns
+func (h *nodeHeads) poll(ctx context.Context, messages chan<- events.Message) {
+	defer close(messages)
+	send := func(msg events.Message) bool {
+		select {
+		case messages <- msg:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	blocks := newBlockTracker(0)
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		header, err := h.source.header(ctx, "head")
+		if err != nil {
+			log.Errorf("Failed to get head from node: %v", err)
+			return
+		}
+
+		if lastHash, _ := blocks.hash(blocks.lastLevel()); header.Hash != lastHash {
+			predecessor, ok := blocks.hash(header.Level - 1)
+			if header.Level <= blocks.lastLevel() || (ok && predecessor != header.Predecessor) {
+				ancestor, err := h.commonAncestor(ctx, blocks, header.Level-1)
+				if err != nil {
+					log.Errorf("Failed to find the common ancestor of head %v: %v", header.Hash, err)
+					return
+				}
+				blocks.rollback(ancestor)
+				if !send(events.Message{Channel: events.ChannelHead, Type: events.MessageTypeReorg, State: uint64(ancestor)}) {
+					return
+				}
+			}
+			blocks.add(header.Level, header.Hash)
+			if !send(events.Message{Channel: events.ChannelHead, Type: events.MessageTypeData, Body: header}) {
+				return
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// commonAncestor returns the highest polled head at or below level still in the main chain of the node
+func (h *nodeHeads) commonAncestor(ctx context.Context, blocks *blockTracker, level int32) (int32, error) {
+	for i := len(blocks.levels) - 1; i >= 0; i-- {
+		if blocks.levels[i] > level {
+			continue
+		}
+		header, err := h.source.header(ctx, strconv.Itoa(int(blocks.levels[i])))
+		if err != nil {
+			return 0, err
+		}
+		if known, _ := blocks.hash(blocks.levels[i]); header.Hash == known {
+			return blocks.levels[i], nil
+		}
+	}
+	// the reorganization is deeper than the polled heads, roll back all of them
+	if len(blocks.levels) == 0 {
+		return level, nil
+	}
+	return blocks.levels[0] - 1, nil
+}
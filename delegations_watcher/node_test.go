@@ -0,0 +1,179 @@
+package delegationswatcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	events "github.com/dipdup-net/go-lib/tzkt/events"
+	"github.com/ibraheemacara/tezos-delegation-service/httpclient"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+// blockOperations has a delegation, a failed delegation, a transaction and a delegation emitted by a contract
+const blockOperations = `[[], [], [], [
+	{"hash": "opDelegation", "contents": [{"kind": "delegation", "source": "tz1Delegator", "delegate": "tz1Baker", "metadata": {"operation_result": {"status": "applied"}}}]},
+	{"hash": "opFailed", "contents": [{"kind": "delegation", "source": "tz1Other", "delegate": "tz1Baker", "metadata": {"operation_result": {"status": "backtracked"}}}]},
+	{"hash": "opCall", "contents": [{"kind": "transaction", "source": "tz1Caller", "metadata": {"operation_result": {"status": "applied"},
+		"internal_operation_results": [{"kind": "delegation", "source": "KT1Contract", "result": {"status": "applied"}}]}}]}
+]]`
+
+// newNodeStub serves the RPC of a node whose only delegations are in block 2
+func newNodeStub(t *testing.T) *httptest.Server {
+	t.Helper()
+	routes := map[string]string{
+		"/chains/main/blocks/head/header":                               `{"level": 3, "hash": "BL3", "predecessor": "BL2", "timestamp": "2024-01-01T00:00:30Z"}`,
+		"/chains/main/blocks/1/operations":                              `[[], [], [], []]`,
+		"/chains/main/blocks/2/operations":                              blockOperations,
+		"/chains/main/blocks/3/operations":                              `[[], [], [], []]`,
+		"/chains/main/blocks/2/header":                                  `{"level": 2, "hash": "BL2", "predecessor": "BL1", "timestamp": "2024-01-01T00:00:20Z"}`,
+		"/chains/main/blocks/2/context/contracts/tz1Delegator/balance":  `"1500"`,
+		"/chains/main/blocks/1/context/contracts/tz1Delegator/delegate": `"tz1PrevBaker"`,
+		"/chains/main/blocks/2/context/contracts/KT1Contract/balance":   `"20"`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNodeSourceDelegations(t *testing.T) {
+	server := newNodeStub(t)
+	source := newNodeSource(server.URL, httpclient.NewHttpClient(time.Second, httpclient.RetryPolicy{}, httpclient.RateLimit{}, 0))
+
+	var delegations []types.TzktDelegationsResponse
+	if err := source.Delegations(context.Background(), 0, 0, collectPages(&delegations)); err != nil {
+		t.Fatal(err)
+	}
+	if len(delegations) != 2 {
+		t.Fatalf("expected 2 delegations, got %d", len(delegations))
+	}
+
+	delegation := delegations[0]
+	if delegation.Hash != "opDelegation" || delegation.Level != 2 || delegation.Sender.Address != "tz1Delegator" || delegation.Amount != 1500 {
+		t.Errorf("unexpected delegation: %+v", delegation)
+	}
+	if delegation.NewDelegate == nil || delegation.NewDelegate.Address != "tz1Baker" {
+		t.Errorf("expected new delegate tz1Baker, got %v", delegation.NewDelegate)
+	}
+	if delegation.PrevDelegate == nil || delegation.PrevDelegate.Address != "tz1PrevBaker" {
+		t.Errorf("expected previous delegate tz1PrevBaker, got %v", delegation.PrevDelegate)
+	}
+	if !delegation.Timestamp.Equal(time.Date(2024, 1, 1, 0, 0, 20, 0, time.UTC)) {
+		t.Errorf("expected timestamp of block 2, got %v", delegation.Timestamp)
+	}
+
+	// the contract removed its delegate and had none before
+	internal := delegations[1]
+	if internal.Sender.Address != "KT1Contract" || internal.NewDelegate != nil || internal.PrevDelegate != nil || internal.Amount != 20 {
+		t.Errorf("unexpected internal delegation: %+v", internal)
+	}
+	if delegation.Id == internal.Id {
+		t.Errorf("expected distinct ids, got %v", delegation.Id)
+	}
+}
+
+func TestNodeSourceHeadLevel(t *testing.T) {
+	server := newNodeStub(t)
+	source := newNodeSource(server.URL, httpclient.NewHttpClient(time.Second, httpclient.RetryPolicy{}, httpclient.RateLimit{}, 0))
+
+	level, err := source.HeadLevel(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level != 3 {
+		t.Errorf("expected head level 3, got %d", level)
+	}
+}
+
+func TestNodeSourceBlockError(t *testing.T) {
+	server := newNodeStub(t)
+	source := newNodeSource(server.URL, httpclient.NewHttpClient(time.Second, httpclient.RetryPolicy{}, httpclient.RateLimit{}, 0))
+
+	// block 4 is unknown to the node
+	err := source.Delegations(context.Background(), 0, 4, func([]types.TzktDelegationsResponse) error { return nil })
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+// headsStub serves a head that can be replaced during the test
+type headsStub struct {
+	mu      sync.Mutex
+	headers map[string]string
+}
+
+func (s *headsStub) set(block, header string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.headers[block] = header
+}
+
+func (s *headsStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var block string
+	if _, err := fmt.Sscanf(r.URL.Path, "/chains/main/blocks/%s", &block); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	header, ok := s.headers[block]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprint(w, header)
+}
+
+func TestNodeHeads(t *testing.T) {
+	stub := &headsStub{headers: map[string]string{}}
+	stub.set("head/header", `{"level": 10, "hash": "BLa", "predecessor": "BL9"}`)
+	stub.set("10/header", `{"level": 10, "hash": "BLa", "predecessor": "BL9"}`)
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	heads := newNodeHeads(newNodeSource(server.URL, httpclient.NewHttpClient(time.Second, httpclient.RetryPolicy{}, httpclient.RateLimit{}, 0)), 5*time.Millisecond)
+	if err := heads.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer heads.Close()
+
+	next := func() events.Message {
+		t.Helper()
+		select {
+		case msg := <-heads.Listen():
+			return msg
+		case <-time.After(time.Second):
+			t.Fatal("no message received")
+			return events.Message{}
+		}
+	}
+
+	if msg := next(); msg.Type != events.MessageTypeData || msg.Body.(nodeBlockHeader).Level != 10 {
+		t.Fatalf("expected head 10, got %v", msg)
+	}
+
+	stub.set("head/header", `{"level": 11, "hash": "BLb", "predecessor": "BLa"}`)
+	if msg := next(); msg.Type != events.MessageTypeData || msg.Body.(nodeBlockHeader).Hash != "BLb" {
+		t.Fatalf("expected head BLb, got %v", msg)
+	}
+
+	// block 11 is replaced, the node rolls back to block 10
+	stub.set("head/header", `{"level": 11, "hash": "BLc", "predecessor": "BLa"}`)
+	if msg := next(); msg.Type != events.MessageTypeReorg || msg.State != 10 {
+		t.Fatalf("expected reorg to block 10, got %v", msg)
+	}
+	if msg := next(); msg.Type != events.MessageTypeData || msg.Body.(nodeBlockHeader).Hash != "BLc" {
+		t.Fatalf("expected head BLc, got %v", msg)
+	}
+}
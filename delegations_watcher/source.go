@@ -0,0 +1,44 @@
+package delegationswatcher
+
+import (
+	"context"
+
+	events "github.com/dipdup-net/go-lib/tzkt/events"
+	"github.com/ibraheemacara/tezos-delegation-service/httpclient"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+// Source provides the delegations ingested by the watcher
+type Source interface {
+	// HeadLevel returns the level of the last block known by the source
+	HeadLevel(ctx context.Context) (int32, error)
+	// Delegations calls onPage with every page of delegations of the blocks in ]fromLevel, toLevel],
+	// toLevel 0 means up to the current head. Only the last block of a page of pageLimit delegations may be incomplete.
+	Delegations(ctx context.Context, fromLevel, toLevel int32, onPage func([]types.TzktDelegationsResponse) error) error
+}
+
+// HeadsClient notifies the watcher of new heads and chain reorganizations
+type HeadsClient interface {
+	Connect(ctx context.Context) error
+	SubscribeToHead() error
+	Listen() <-chan events.Message
+	Close() error
+}
+
+// tzktSource reads the delegations indexed by tzkt
+type tzktSource struct {
+	url        string
+	httpClient httpclient.HttpInterface
+}
+
+func newTzktSource(url string, httpClient httpclient.HttpInterface) *tzktSource {
+	return &tzktSource{url: url, httpClient: httpClient}
+}
+
+func (s *tzktSource) HeadLevel(ctx context.Context) (int32, error) {
+	return getHeadLevel(ctx, s.url, s.httpClient)
+}
+
+func (s *tzktSource) Delegations(ctx context.Context, fromLevel, toLevel int32, onPage func([]types.TzktDelegationsResponse) error) error {
+	return getDelegations(ctx, s.url, fromLevel, toLevel, s.httpClient, onPage)
+}
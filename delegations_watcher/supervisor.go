@@ -0,0 +1,37 @@
+package delegationswatcher
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// delays before a failed task is restarted, the delay doubles after each failure
+var (
+	minRestartDelay = time.Second
+	maxRestartDelay = time.Minute
+)
+
+// supervise runs task until it succeeds, a failed task is restarted after a delay.
+// It only returns an error once ctx is cancelled.
+func supervise(ctx context.Context, name string, task func(ctx context.Context) error) error {
+	delay := minRestartDelay
+	for {
+		err := task(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Errorf("%s failed: %v, restarting in %v", name, err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay = min(delay*2, maxRestartDelay)
+	}
+}
@@ -0,0 +1,106 @@
+package delegationswatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	events "github.com/dipdup-net/go-lib/tzkt/events"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+func setRestartDelays(t *testing.T, minDelay, maxDelay time.Duration) {
+	t.Helper()
+	previousMin, previousMax := minRestartDelay, maxRestartDelay
+	minRestartDelay, maxRestartDelay = minDelay, maxDelay
+	t.Cleanup(func() { minRestartDelay, maxRestartDelay = previousMin, previousMax })
+}
+
+func TestSupervise(t *testing.T) {
+	setRestartDelays(t, time.Millisecond, 2*time.Millisecond)
+
+	runs := 0
+	err := supervise(context.Background(), "task", func(ctx context.Context) error {
+		runs++
+		if runs < 3 {
+			return fmt.Errorf("transient error")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs != 3 {
+		t.Errorf("expected 3 runs, got %d", runs)
+	}
+}
+
+func TestSuperviseCancelled(t *testing.T) {
+	setRestartDelays(t, time.Hour, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+	done := make(chan error)
+	go func() {
+		done <- supervise(ctx, "task", func(ctx context.Context) error {
+			runs++
+			return fmt.Errorf("permanent error")
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("supervise did not stop after the context was cancelled")
+	}
+	if runs != 1 {
+		t.Errorf("expected 1 run, got %d", runs)
+	}
+}
+
+// flakySource fails the first delegations requests
+type flakySource struct {
+	Source
+	failures int
+	calls    int
+}
+
+func (f *flakySource) Delegations(ctx context.Context, fromLevel, toLevel int32, onPage func([]types.TzktDelegationsResponse) error) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return fmt.Errorf("source down")
+	}
+	return f.Source.Delegations(ctx, fromLevel, toLevel, onPage)
+}
+
+func TestStartRestartsBackfill(t *testing.T) {
+	setRestartDelays(t, time.Millisecond, time.Millisecond)
+
+	source := &flakySource{Source: &MockSource{failAfter: -1}, failures: 2}
+	watcher := &DelegationsWatcher{
+		source: source,
+		db:     &MockDB{},
+		heads:  &MockTzkt{msgChan: make(chan events.Message)},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watcher.Start(ctx)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	if source.calls != 3 {
+		t.Errorf("expected the backfill to run 3 times, got %d", source.calls)
+	}
+}
@@ -0,0 +1,125 @@
+package delegationswatcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	"github.com/penglongli/gin-metrics/ginmetrics"
+	log "github.com/sirupsen/logrus"
+)
+
+const discrepanciesMetric = "delegations_source_discrepancies"
+
+var registerDiscrepanciesMetric sync.Once
+
+// the verifier is usually a block or two behind the source, its head is checked verifierAttempts times before giving up
+var (
+	verifierAttempts   = 3
+	verifierRetryDelay = time.Second
+)
+
+// verify fetches the delegations of ]fromLevel, toLevel] from the verifier and reports the differences with delegations.
+// The delegations are stored whatever the outcome, a verifier lagging behind the chain would otherwise stop the ingestion.
+// Blocks the verifier hasn't reached yet are not verified rather than reported as missing from it.
+func (dw *DelegationsWatcher) verify(ctx context.Context, fromLevel, toLevel int32, delegations []types.TzktDelegationsResponse) {
+	if !dw.verifierReached(ctx, toLevel) {
+		log.Warnf("Blocks %v to %v not verified, the verification source is behind", fromLevel+1, toLevel)
+		return
+	}
+
+	var expected []types.TzktDelegationsResponse
+	err := dw.verifier.Delegations(ctx, fromLevel, toLevel, func(page []types.TzktDelegationsResponse) error {
+		expected = append(expected, page...)
+		return nil
+	})
+	if err != nil {
+		log.Warnf("Failed to verify blocks %v to %v: %v", fromLevel+1, toLevel, err)
+		return
+	}
+
+	discrepancies := compareDelegations(delegations, expected)
+	for _, discrepancy := range discrepancies {
+		log.Warnf("Blocks %v to %v: %s", fromLevel+1, toLevel, discrepancy)
+	}
+	if len(discrepancies) > 0 {
+		reportDiscrepancies(len(discrepancies))
+	}
+}
+
+// verifierReached waits for the head of the verifier to reach level, it returns false once the attempts are exhausted
+func (dw *DelegationsWatcher) verifierReached(ctx context.Context, level int32) bool {
+	for attempt := 1; ; attempt++ {
+		head, err := dw.verifier.HeadLevel(ctx)
+		if err != nil {
+			log.Warnf("Failed to get the head of the verification source: %v", err)
+			return false
+		}
+		if head >= level {
+			return true
+		}
+		if attempt == verifierAttempts {
+			return false
+		}
+		select {
+		case <-time.After(verifierRetryDelay):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// compareDelegations describes the delegations missing from one of the lists or that differ between them
+func compareDelegations(delegations, expected []types.TzktDelegationsResponse) []string {
+	byId := make(map[int64]types.TzktDelegationsResponse, len(expected))
+	for _, delegation := range expected {
+		byId[delegation.Id] = delegation
+	}
+
+	var discrepancies []string
+	for _, delegation := range delegations {
+		other, ok := byId[delegation.Id]
+		if !ok {
+			discrepancies = append(discrepancies, fmt.Sprintf("operation %v missing from the verification source", delegation.Id))
+			continue
+		}
+		delete(byId, delegation.Id)
+		if !sameDelegation(delegation, other) {
+			discrepancies = append(discrepancies, fmt.Sprintf("operation %v differs: %+v, verification source has %+v", delegation.Id, delegation, other))
+		}
+	}
+	for id := range byId {
+		discrepancies = append(discrepancies, fmt.Sprintf("operation %v only found in the verification source", id))
+	}
+	return discrepancies
+}
+
+func sameDelegation(a, b types.TzktDelegationsResponse) bool {
+	return a.Hash == b.Hash &&
+		a.Level == b.Level &&
+		a.Timestamp.Equal(b.Timestamp) &&
+		a.Sender.Address == b.Sender.Address &&
+		a.Amount == b.Amount &&
+		addressOf(a.NewDelegate) == addressOf(b.NewDelegate) &&
+		addressOf(a.PrevDelegate) == addressOf(b.PrevDelegate)
+}
+
+// reportDiscrepancies counts the discrepancies in a metric that alerts can be set on
+func reportDiscrepancies(count int) {
+	monitor := ginmetrics.GetMonitor()
+	registerDiscrepanciesMetric.Do(func() {
+		err := monitor.AddMetric(&ginmetrics.Metric{
+			Type:        ginmetrics.Counter,
+			Name:        discrepanciesMetric,
+			Description: "Number of delegations differing between the source and the verification source",
+		})
+		if err != nil {
+			log.Errorf("failed to add metric %v: %v", discrepanciesMetric, err)
+		}
+	})
+	if err := monitor.GetMetric(discrepanciesMetric).Add(nil, float64(count)); err != nil {
+		log.Errorf("error while incrementing metric %v: %v", discrepanciesMetric, err)
+	}
+}
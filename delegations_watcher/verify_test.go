@@ -0,0 +1,80 @@
+package delegationswatcher
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+func TestCompareDelegations(t *testing.T) {
+	delegations := []types.TzktDelegationsResponse{
+		{Id: 1, Level: 10, Amount: 100},
+		{Id: 2, Level: 10, Amount: 200},
+		{Id: 3, Level: 11, Amount: 300},
+	}
+	expected := []types.TzktDelegationsResponse{
+		{Id: 1, Level: 10, Amount: 100},
+		{Id: 2, Level: 10, Amount: 250},
+		{Id: 4, Level: 11, Amount: 400},
+	}
+
+	discrepancies := compareDelegations(delegations, expected)
+	if len(discrepancies) != 3 {
+		t.Fatalf("expected 3 discrepancies, got %v", discrepancies)
+	}
+	for i, expected := range []string{"operation 2 differs", "operation 3 missing", "operation 4 only found"} {
+		if !strings.HasPrefix(discrepancies[i], expected) {
+			t.Errorf("expected discrepancy %q, got %q", expected, discrepancies[i])
+		}
+	}
+
+	if discrepancies := compareDelegations(delegations, delegations); len(discrepancies) != 0 {
+		t.Errorf("expected no discrepancy, got %v", discrepancies)
+	}
+}
+
+func TestProcessHeadVerify(t *testing.T) {
+	mockDB := &MockDB{}
+	verifier := &MockSource{head: 11, failAfter: -1}
+	watcher := &DelegationsWatcher{
+		source:   &MockSource{failAfter: -1},
+		db:       mockDB,
+		verifier: verifier,
+	}
+
+	if err := watcher.processHead(context.Background(), newBlockTracker(9), 11, "BLa"); err != nil {
+		t.Fatal(err)
+	}
+	// each page is stored then verified as it arrives
+	if len(verifier.fromLevels) != 2 || verifier.fromLevels[0] != 9 || verifier.fromLevels[1] != 10 {
+		t.Errorf("expected blocks 10 then 11 to be verified, got %v", verifier.fromLevels)
+	}
+	if mockDB.BulkInsertCount != 2 {
+		t.Errorf("expected a page inserted per block, got %d", mockDB.BulkInsertCount)
+	}
+}
+
+func TestProcessHeadVerifyLagging(t *testing.T) {
+	previousDelay := verifierRetryDelay
+	verifierRetryDelay = time.Millisecond
+	t.Cleanup(func() { verifierRetryDelay = previousDelay })
+
+	// the verifier has not received block 11 yet
+	verifier := &MockSource{head: 10, failAfter: -1}
+	watcher := &DelegationsWatcher{
+		source:   &MockSource{failAfter: -1},
+		db:       &MockDB{},
+		verifier: verifier,
+	}
+
+	if err := watcher.processHead(context.Background(), newBlockTracker(9), 11, "BLa"); err != nil {
+		t.Fatal(err)
+	}
+	// block 11 is not compared rather than reported as missing from the verifier
+	if len(verifier.fromLevels) != 1 || verifier.fromLevels[0] != 9 {
+		t.Errorf("expected only block 10 to be verified, got %v", verifier.fromLevels)
+	}
+}
@@ -0,0 +1,176 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without sending the request while the circuit of the server is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type circuitState int
+
+// the values are the ones of the circuit breaker state metric
+const (
+	circuitClosed circuitState = iota
+	circuitHalfOpen
+	circuitOpen
+)
+
+// circuit is the state of the requests to one host
+type circuit struct {
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+// CircuitBreaker stops sending requests to a host after consecutive failures. Once the cooldown is over,
+// a single trial request is sent: the circuit closes if it succeeds and opens again if it fails.
+type CircuitBreaker struct {
+	HttpInterface
+	failureThreshold int
+	cooldown         time.Duration
+	now              func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// NewCircuitBreaker opens the circuit of a host after failureThreshold consecutive failures, for cooldown
+func NewCircuitBreaker(client HttpInterface, failureThreshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		HttpInterface:    client,
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		now:              time.Now,
+		circuits:         make(map[string]*circuit),
+	}
+}
+
+func (b *CircuitBreaker) Get(ctx context.Context, rawUrl string) ([]byte, error) {
+	host := hostOf(rawUrl)
+	if err := b.allow(host); err != nil {
+		return nil, err
+	}
+	data, err := b.HttpInterface.Get(ctx, rawUrl)
+	b.record(ctx, host, err)
+	return data, err
+}
+
+func (b *CircuitBreaker) Stream(ctx context.Context, rawUrl string, read func(body io.Reader) error) error {
+	host := hostOf(rawUrl)
+	if err := b.allow(host); err != nil {
+		return err
+	}
+	// once the body is received, errors returned by read are the caller's and only errors reading the body are failures
+	var received bool
+	var bodyErr error
+	err := b.HttpInterface.Stream(ctx, rawUrl, func(body io.Reader) error {
+		received = true
+		return read(&errorRecorder{reader: body, err: &bodyErr})
+	})
+	if received {
+		b.record(ctx, host, bodyErr)
+	} else {
+		b.record(ctx, host, err)
+	}
+	return err
+}
+
+// errorRecorder keeps the error met reading the body
+type errorRecorder struct {
+	reader io.Reader
+	err    *error
+}
+
+func (r *errorRecorder) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && err != io.EOF {
+		*r.err = err
+	}
+	return n, err
+}
+
+// allow tells if a request can be sent to host
+func (b *CircuitBreaker) allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(host)
+	switch c.state {
+	case circuitOpen:
+		if b.now().Sub(c.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		// the caller sends the trial request
+		b.setState(host, c, circuitHalfOpen)
+		return nil
+	case circuitHalfOpen:
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// record updates the circuit of host with the outcome of a request
+func (b *CircuitBreaker) record(ctx context.Context, host string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(host)
+
+	// a cancelled request tells nothing about the server, the next request is the trial instead
+	if ctx.Err() != nil {
+		if c.state == circuitHalfOpen {
+			b.setState(host, c, circuitOpen)
+		}
+		return
+	}
+
+	if !isFailure(err) {
+		c.failures = 0
+		b.setState(host, c, circuitClosed)
+		return
+	}
+	c.failures++
+	if c.state == circuitHalfOpen || c.failures >= b.failureThreshold {
+		c.openedAt = b.now()
+		b.setState(host, c, circuitOpen)
+	}
+}
+
+func (b *CircuitBreaker) circuit(host string) *circuit {
+	c, ok := b.circuits[host]
+	if !ok {
+		c = &circuit{}
+		b.circuits[host] = c
+	}
+	return c
+}
+
+func (b *CircuitBreaker) setState(host string, c *circuit, state circuitState) {
+	c.state = state
+	setCircuitState(host, state)
+}
+
+// isFailure tells if err means that the server is unhealthy, client errors such as a 404 don't
+func isFailure(err error) bool {
+	if err == nil || errors.Is(err, ErrNotFound) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+func hostOf(rawUrl string) string {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	return parsed.Host
+}
@@ -0,0 +1,115 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// MockHttpClient returns err for every request
+type MockHttpClient struct {
+	err   error
+	calls int
+}
+
+func (m *MockHttpClient) Get(ctx context.Context, url string) ([]byte, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []byte("[]"), nil
+}
+
+func (m *MockHttpClient) Stream(ctx context.Context, url string, read func(io.Reader) error) error {
+	data, err := m.Get(ctx, url)
+	if err != nil {
+		return err
+	}
+	return read(bytes.NewReader(data))
+}
+
+func newTestBreaker(client HttpInterface) (*CircuitBreaker, *time.Time) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(client, 3, time.Minute)
+	breaker.now = func() time.Time { return now }
+	return breaker, &now
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	client := &MockHttpClient{err: &StatusError{StatusCode: http.StatusBadGateway}}
+	breaker, _ := newTestBreaker(client)
+
+	for range 3 {
+		if _, err := breaker.Get(context.Background(), "http://tzkt/v1/head"); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("circuit opened too early")
+		}
+	}
+	if _, err := breaker.Get(context.Background(), "http://tzkt/v1/head"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected open circuit, got %v", err)
+	}
+	if client.calls != 3 {
+		t.Errorf("expected 3 requests sent, got %d", client.calls)
+	}
+
+	// other hosts are not affected
+	if _, err := breaker.Get(context.Background(), "http://other/v1/head"); errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected the circuit of another host to be closed")
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	client := &MockHttpClient{err: fmt.Errorf("connection refused")}
+	breaker, now := newTestBreaker(client)
+	for range 3 {
+		_, _ = breaker.Get(context.Background(), "http://tzkt/v1/head")
+	}
+
+	// the trial request fails and the circuit opens again
+	*now = now.Add(time.Minute)
+	if _, err := breaker.Get(context.Background(), "http://tzkt/v1/head"); errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected a trial request after the cooldown")
+	}
+	if _, err := breaker.Get(context.Background(), "http://tzkt/v1/head"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected open circuit after the failed trial, got %v", err)
+	}
+
+	// the trial request succeeds and the circuit closes
+	*now = now.Add(time.Minute)
+	client.err = nil
+	for range 2 {
+		if _, err := breaker.Get(context.Background(), "http://tzkt/v1/head"); err != nil {
+			t.Errorf("expected closed circuit, got %v", err)
+		}
+	}
+}
+
+func TestCircuitBreakerClientErrors(t *testing.T) {
+	client := &MockHttpClient{err: ErrNotFound}
+	breaker, _ := newTestBreaker(client)
+
+	for range 5 {
+		if _, err := breaker.Get(context.Background(), "http://tzkt/v1/head"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected not found, got %v", err)
+		}
+	}
+}
+
+func TestCircuitBreakerStreamCallerError(t *testing.T) {
+	client := &MockHttpClient{}
+	breaker, _ := newTestBreaker(client)
+
+	// errors of the caller reading the body are not failures of the server
+	for range 5 {
+		err := breaker.Stream(context.Background(), "http://tzkt/v1/head", func(io.Reader) error {
+			return fmt.Errorf("insert error")
+		})
+		if errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected closed circuit, got %v", err)
+		}
+	}
+}
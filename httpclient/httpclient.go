@@ -1,48 +1,151 @@
 package httpclient
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 )
 
+// ErrNotFound is returned when the server answers with a 404
+var ErrNotFound = errors.New("not found")
+
+// ErrResponseTooLarge is returned when a response body is above the maximum size of the client
+var ErrResponseTooLarge = errors.New("response too large")
+
 type HttpInterface interface {
-	Get(url string) ([]byte, error)
+	// Get returns the body of the response to url
+	Get(ctx context.Context, url string) ([]byte, error)
+	// Stream calls read with the body of the response to url, the body is read from the network as read consumes it.
+	// Failed requests are only retried when read was not called yet.
+	Stream(ctx context.Context, url string, read func(body io.Reader) error) error
 }
 
 type HttpClient struct {
 	client *http.Client
+	retry  RetryPolicy
+	// limiter, if any, hands a token to every attempt of a request
+	limiter *rate.Limiter
+	// maxResponseSize is the maximum size of a response body in bytes, 0 means no limit
+	maxResponseSize int64
+	sleep           func(ctx context.Context, d time.Duration) error
 }
 
-func NewHttpClient(timeout time.Duration) *HttpClient {
+func NewHttpClient(timeout time.Duration, retry RetryPolicy, rateLimit RateLimit, maxResponseSize int64) *HttpClient {
 	return &HttpClient{
 		client: &http.Client{
 			Timeout: timeout,
 		},
+		retry:           retry,
+		limiter:         rateLimit.limiter(),
+		maxResponseSize: maxResponseSize,
+		sleep:           Sleep,
+	}
+}
+
+func (c *HttpClient) Get(ctx context.Context, url string) ([]byte, error) {
+	var data []byte
+	err := c.Stream(ctx, url, func(body io.Reader) error {
+		var err error
+		data, err = io.ReadAll(body)
+		return err
+	})
+	return data, err
+}
+
+func (c *HttpClient) Stream(ctx context.Context, url string, read func(body io.Reader) error) error {
+	attempts := max(c.retry.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		// a retry, after a 429 above all, is a request like the others for the rate limit of the server
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+		resp, err := c.do(ctx, url)
+		if err == nil {
+			defer resp.Body.Close()
+			return read(&limitedReader{reader: resp.Body, remaining: c.maxResponseSize, limited: c.maxResponseSize > 0})
+		}
+		if attempt >= attempts || ctx.Err() != nil || !c.retry.retryable(err) {
+			return err
+		}
+
+		wait := c.retry.Backoff(attempt, err)
+		log.Warnf("Request to %s failed: %v, attempt %d/%d, retrying in %v", url, err, attempt, attempts, wait)
+		if err := c.sleep(ctx, wait); err != nil {
+			return err
+		}
 	}
 }
 
-func (c *HttpClient) Get(url string) ([]byte, error) {
-	resp, err := c.client.Get(url)
+// do sends the request and returns the response when its status is 200
+func (c *HttpClient) do(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	defer resp.Body.Close()
-	data, err := io.ReadAll(resp.Body)
+	start := time.Now()
+	resp, err := c.client.Do(req)
 	if err != nil {
+		observeRequest(req.URL.Host, "error", time.Since(start))
 		return nil, err
 	}
+	observeRequest(req.URL.Host, fmt.Sprint(resp.StatusCode), time.Since(start))
+
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	// the body of an error is drained so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, errors.New("not found")
+		return nil, ErrNotFound
 	}
+	return nil, &StatusError{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("non 200 status code: %v", resp.StatusCode)
+// limitedReader fails with ErrResponseTooLarge once more than remaining bytes are read
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+	limited   bool
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if !r.limited {
+		return r.reader.Read(p)
+	}
+	if r.remaining < 0 {
+		return 0, ErrResponseTooLarge
 	}
+	// one byte more than allowed is read to tell a body of the maximum size from a larger one
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, ErrResponseTooLarge
+	}
+	return n, err
+}
 
-	return data, nil
+// Sleep waits for d or until ctx is cancelled
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -1 +1,237 @@
 package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newFlakyServer answers with the statuses in order then with a 200
+func newFlakyServer(t *testing.T, headers map[string]string, statuses ...int) (*httptest.Server, *int) {
+	t.Helper()
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= len(statuses) {
+			for key, value := range headers {
+				w.Header().Set(key, value)
+			}
+			w.WriteHeader(statuses[calls-1])
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+// newTestClient records the waits instead of sleeping
+func newTestClient(retry RetryPolicy) (*HttpClient, *[]time.Duration) {
+	var waits []time.Duration
+	client := NewHttpClient(time.Second, retry, RateLimit{}, 0)
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return client, &waits
+}
+
+var testPolicy = RetryPolicy{
+	MaxAttempts:        3,
+	InitialBackoff:     100 * time.Millisecond,
+	MaxBackoff:         time.Second,
+	RetryStatusCodes:   []int{http.StatusBadGateway, http.StatusTooManyRequests},
+	RetryNetworkErrors: true,
+}
+
+func TestGetRetry(t *testing.T) {
+	server, calls := newFlakyServer(t, nil, http.StatusBadGateway, http.StatusBadGateway)
+	client, waits := newTestClient(testPolicy)
+
+	data, err := client.Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "ok" || *calls != 3 {
+		t.Errorf("expected ok after 3 calls, got %q after %d", data, *calls)
+	}
+	if len(*waits) != 2 {
+		t.Fatalf("expected 2 waits, got %v", *waits)
+	}
+	// the backoff doubles with a jitter of half the backoff at most
+	if (*waits)[0] < 50*time.Millisecond || (*waits)[0] > 100*time.Millisecond || (*waits)[1] < 100*time.Millisecond || (*waits)[1] > 200*time.Millisecond {
+		t.Errorf("unexpected backoff %v", *waits)
+	}
+}
+
+func TestGetMaxAttempts(t *testing.T) {
+	server, calls := newFlakyServer(t, nil, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	client, _ := newTestClient(testPolicy)
+
+	_, err := client.Get(context.Background(), server.URL)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected status error 502, got %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 calls, got %d", *calls)
+	}
+}
+
+func TestGetNotRetried(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusNotFound} {
+		server, calls := newFlakyServer(t, nil, status)
+		client, _ := newTestClient(testPolicy)
+
+		if _, err := client.Get(context.Background(), server.URL); err == nil {
+			t.Errorf("expected error for status %d, got nil", status)
+		}
+		if *calls != 1 {
+			t.Errorf("expected 1 call for status %d, got %d", status, *calls)
+		}
+	}
+}
+
+func TestGetNotFound(t *testing.T) {
+	server, _ := newFlakyServer(t, nil, http.StatusNotFound)
+	client, _ := newTestClient(RetryPolicy{})
+
+	if _, err := client.Get(context.Background(), server.URL); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestGetRetryAfter(t *testing.T) {
+	server, _ := newFlakyServer(t, map[string]string{"Retry-After": "3"}, http.StatusTooManyRequests)
+	client, waits := newTestClient(testPolicy)
+
+	if _, err := client.Get(context.Background(), server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if len(*waits) != 1 || (*waits)[0] != 3*time.Second {
+		t.Errorf("expected to wait 3s as asked by the server, got %v", *waits)
+	}
+}
+
+func TestGetNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	client, waits := newTestClient(testPolicy)
+	if _, err := client.Get(context.Background(), url); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if len(*waits) != 2 {
+		t.Errorf("expected 2 retries, got %v", *waits)
+	}
+
+	policy := testPolicy
+	policy.RetryNetworkErrors = false
+	client, waits = newTestClient(policy)
+	if _, err := client.Get(context.Background(), url); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if len(*waits) != 0 {
+		t.Errorf("expected no retry, got %v", *waits)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("120"); d != 2*time.Minute {
+		t.Errorf("expected 2m, got %v", d)
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if d := parseRetryAfter(date); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expected about 1h, got %v", d)
+	}
+	if d := parseRetryAfter("soon"); d != 0 {
+		t.Errorf("expected 0, got %v", d)
+	}
+}
+
+func TestGetMaxResponseSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "0123456789")
+	}))
+	defer server.Close()
+
+	client := NewHttpClient(time.Second, RetryPolicy{}, RateLimit{}, 10)
+	data, err := client.Get(context.Background(), server.URL)
+	if err != nil || string(data) != "0123456789" {
+		t.Errorf("expected a body of the maximum size to be read, got %q, %v", data, err)
+	}
+
+	client = NewHttpClient(time.Second, RetryPolicy{}, RateLimit{}, 9)
+	if _, err := client.Get(context.Background(), server.URL); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("expected response too large, got %v", err)
+	}
+}
+
+func TestGetCancelled(t *testing.T) {
+	server, calls := newFlakyServer(t, nil, http.StatusBadGateway, http.StatusBadGateway)
+	client := NewHttpClient(time.Second, RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour, RetryStatusCodes: []int{http.StatusBadGateway}}, RateLimit{}, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	// the backoff is interrupted by the cancellation
+	if _, err := client.Get(ctx, server.URL); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled, got %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("expected 1 call, got %d", *calls)
+	}
+}
+
+func TestDecodeArray(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id": 1}, {"id": 2}, {"id": 3}]`)
+	}))
+	defer server.Close()
+	client := NewHttpClient(time.Second, RetryPolicy{}, RateLimit{}, 0)
+
+	type element struct {
+		Id int `json:"id"`
+	}
+	var ids []int
+	err := DecodeArray(context.Background(), client, server.URL, func(e element) error {
+		ids = append(ids, e.Id)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+		t.Errorf("expected ids 1 to 3, got %v", ids)
+	}
+
+	// decoding stops at the first element failing
+	calls := 0
+	err = DecodeArray(context.Background(), client, server.URL, func(e element) error {
+		calls++
+		return fmt.Errorf("element error")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected error after 1 element, got %v after %d", err, calls)
+	}
+}
+
+func TestDecodeArrayNotArray(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 1}`)
+	}))
+	defer server.Close()
+	client := NewHttpClient(time.Second, RetryPolicy{}, RateLimit{}, 0)
+
+	err := DecodeArray(context.Background(), client, server.URL, func(e map[string]any) error { return nil })
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
@@ -0,0 +1,46 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DecodeArray decodes the JSON array returned by url one element at a time, the response is never fully buffered
+func DecodeArray[T any](ctx context.Context, client HttpInterface, url string, onElement func(T) error) error {
+	return client.Stream(ctx, url, func(body io.Reader) error {
+		decoder := json.NewDecoder(body)
+		if err := expectDelim(decoder, '['); err != nil {
+			return err
+		}
+		for decoder.More() {
+			var element T
+			if err := decoder.Decode(&element); err != nil {
+				return err
+			}
+			if err := onElement(element); err != nil {
+				return err
+			}
+		}
+		return expectDelim(decoder, ']')
+	})
+}
+
+// DecodeJSON decodes the JSON value returned by url into value
+func DecodeJSON(ctx context.Context, client HttpInterface, url string, value any) error {
+	return client.Stream(ctx, url, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(value)
+	})
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v in JSON response, got %v", delim, token)
+	}
+	return nil
+}
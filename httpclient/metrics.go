@@ -0,0 +1,57 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+
+	"github.com/penglongli/gin-metrics/ginmetrics"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	requestDurationMetric = "http_client_request_duration_seconds"
+	circuitStateMetric    = "http_client_circuit_breaker_state"
+)
+
+var registerMetrics sync.Once
+
+func addMetrics(monitor *ginmetrics.Monitor) {
+	metrics := []*ginmetrics.Metric{
+		{
+			Type:        ginmetrics.Histogram,
+			Name:        requestDurationMetric,
+			Description: "Duration of the requests sent to tzkt or the node by host and status",
+			Labels:      []string{"host", "status"},
+			Buckets:     []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		},
+		{
+			Type:        ginmetrics.Gauge,
+			Name:        circuitStateMetric,
+			Description: "State of the circuit breaker by host: 0 closed, 1 half-open, 2 open",
+			Labels:      []string{"host"},
+		},
+	}
+	for _, metric := range metrics {
+		if err := monitor.AddMetric(metric); err != nil {
+			log.Errorf("failed to add metric %v: %v", metric.Name, err)
+		}
+	}
+}
+
+// observeRequest records the duration and the status of a request, status is "error" when no response was received
+func observeRequest(host, status string, duration time.Duration) {
+	monitor := ginmetrics.GetMonitor()
+	registerMetrics.Do(func() { addMetrics(monitor) })
+	if err := monitor.GetMetric(requestDurationMetric).Observe([]string{host, status}, duration.Seconds()); err != nil {
+		log.Errorf("error while observing metric %v: %v", requestDurationMetric, err)
+	}
+}
+
+// setCircuitState exposes the state of the circuit breaker of host
+func setCircuitState(host string, state circuitState) {
+	monitor := ginmetrics.GetMonitor()
+	registerMetrics.Do(func() { addMetrics(monitor) })
+	if err := monitor.GetMetric(circuitStateMetric).SetGaugeValue([]string{host}, float64(state)); err != nil {
+		log.Errorf("error while setting metric %v: %v", circuitStateMetric, err)
+	}
+}
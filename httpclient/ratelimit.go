@@ -0,0 +1,22 @@
+package httpclient
+
+import (
+	"golang.org/x/time/rate"
+)
+
+// RateLimit bounds the requests sent by a client, so that concurrent callers stay under the rate limit of the
+// server together. Every attempt of a request waits for a token, the retries included.
+type RateLimit struct {
+	// RequestsPerSecond is the average rate of the requests, 0 means no limit
+	RequestsPerSecond float64
+	// Burst is the number of requests that can be sent at once after an idle period
+	Burst int
+}
+
+// limiter returns the token bucket of the rate limit, nil when there is no limit
+func (l RateLimit) limiter() *rate.Limiter {
+	if l.RequestsPerSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(l.RequestsPerSecond), max(l.Burst, 1))
+}
@@ -0,0 +1,62 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	server, calls := newFlakyServer(t, nil)
+	client := NewHttpClient(time.Second, RetryPolicy{}, RateLimit{RequestsPerSecond: 1000, Burst: 2}, 0)
+
+	start := time.Now()
+	for range 4 {
+		if _, err := client.Get(context.Background(), server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 2 requests of the burst then 2 requests 1ms apart
+	if elapsed := time.Since(start); elapsed < time.Millisecond {
+		t.Errorf("expected requests to be delayed, took %v", elapsed)
+	}
+	if *calls != 4 {
+		t.Errorf("expected 4 requests, got %d", *calls)
+	}
+}
+
+func TestRateLimitRetries(t *testing.T) {
+	server, calls := newFlakyServer(t, nil, http.StatusTooManyRequests, http.StatusTooManyRequests)
+	client := NewHttpClient(time.Second, RetryPolicy{MaxAttempts: 3, RetryStatusCodes: []int{http.StatusTooManyRequests}}, RateLimit{RequestsPerSecond: 50, Burst: 1}, 0)
+
+	start := time.Now()
+	if _, err := client.Get(context.Background(), server.URL); err != nil {
+		t.Fatal(err)
+	}
+	// each of the 3 attempts took a token, the retries waited 20ms each without any backoff
+	if *calls != 3 {
+		t.Errorf("expected 3 requests sent, got %d", *calls)
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("expected the retries to wait for the rate limit, took %v", elapsed)
+	}
+}
+
+func TestRateLimitCancelled(t *testing.T) {
+	server, calls := newFlakyServer(t, nil)
+	client := NewHttpClient(time.Second, RetryPolicy{}, RateLimit{RequestsPerSecond: 0.001, Burst: 1}, 0)
+	if _, err := client.Get(context.Background(), server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	// the next token comes in 1000 seconds
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.Get(ctx, server.URL); err == nil {
+		t.Errorf("expected the wait to fail, got nil")
+	}
+	if *calls != 1 {
+		t.Errorf("expected 1 request, got %d", *calls)
+	}
+}
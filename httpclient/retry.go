@@ -0,0 +1,82 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy tells which failed requests are attempted again and how long to wait before each new attempt
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts of a request, 1 or less means no retry
+	MaxAttempts int
+	// InitialBackoff is the wait before the second attempt, it doubles with each attempt up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// RetryStatusCodes are the response status codes worth another attempt
+	RetryStatusCodes []int
+	// RetryNetworkErrors retries the requests that got no response, timeouts included
+	RetryNetworkErrors bool
+}
+
+// StatusError is returned when the server answers with an unexpected status code
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is the wait requested by the server with the Retry-After header, 0 if none
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("non 200 status code: %v", e.StatusCode)
+}
+
+// retryable tells if err is worth another attempt
+func (p RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, ErrNotFound) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return slices.Contains(p.RetryStatusCodes, statusErr.StatusCode)
+	}
+	return p.RetryNetworkErrors
+}
+
+// Backoff returns the wait after the failed attempt, a random value between half and all of the exponential backoff
+// so that concurrent clients don't retry all at once. The server's Retry-After wins when it asks to wait longer.
+func (p RetryPolicy) Backoff(attempt int, err error) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 {
+		backoff = min(backoff, p.MaxBackoff)
+	}
+	if backoff > 0 {
+		backoff = backoff/2 + rand.N(backoff/2+1)
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		backoff = max(backoff, statusErr.RetryAfter)
+	}
+	return backoff
+}
+
+// parseRetryAfter reads a Retry-After header holding either a number of seconds or a date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
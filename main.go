@@ -1,15 +1,19 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"fmt"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/ibraheemacara/tezos-delegation-service/api"
+	"github.com/ibraheemacara/tezos-delegation-service/broker"
 	"github.com/ibraheemacara/tezos-delegation-service/config"
 	"github.com/ibraheemacara/tezos-delegation-service/db"
 	delegationswatcher "github.com/ibraheemacara/tezos-delegation-service/delegations_watcher"
 	"github.com/ibraheemacara/tezos-delegation-service/httpclient"
+	"github.com/ibraheemacara/tezos-delegation-service/webhooks"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -19,18 +23,58 @@ func main() {
 
 	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
-		fmt.Println("Error loading config:", err)
-		return
+		log.Fatalf("Error loading config: %v", err)
 	}
 
+	// cancelled on SIGINT or SIGTERM, every component stops and drains its work
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	db, err := db.InitDB(cfg)
 	if err != nil {
 		log.Fatalf("Error initializing database: %v", err)
 	}
 
-	httpClient := httpclient.NewHttpClient(4 * time.Second)
-	delegationsWatcher := delegationswatcher.NewDelegationsWatcher(cfg, httpClient, db)
-	go delegationsWatcher.Start()
+	retry := cfg.Http.Retry
+	retryPolicy := httpclient.RetryPolicy{
+		MaxAttempts:        retry.MaxAttempts,
+		InitialBackoff:     retry.InitialBackoff,
+		MaxBackoff:         retry.MaxBackoff,
+		RetryStatusCodes:   retry.StatusCodes,
+		RetryNetworkErrors: retry.NetworkErrors,
+	}
+	// the limiter is shared by every call, the parallel backfill and the watcher included
+	rateLimit := httpclient.RateLimit{RequestsPerSecond: cfg.Http.RateLimit, Burst: cfg.Http.Burst}
+	var httpClient httpclient.HttpInterface = httpclient.NewHttpClient(cfg.Http.Timeout, retryPolicy, rateLimit, cfg.Http.MaxResponseSize)
+	if cfg.Http.CircuitBreaker.Failures > 0 {
+		httpClient = httpclient.NewCircuitBreaker(httpClient, cfg.Http.CircuitBreaker.Failures, cfg.Http.CircuitBreaker.Cooldown)
+	}
+	// carries the delegations of the new blocks from the watcher to the api streams
+	delegationsBroker := broker.NewBroker()
+	dispatcher := webhooks.NewDispatcher(cfg, db)
+	dispatcherDone := make(chan struct{})
+	go func() {
+		defer close(dispatcherDone)
+		dispatcher.Run(ctx)
+	}()
+
+	delegationsWatcher := delegationswatcher.NewDelegationsWatcher(cfg, httpClient, db, delegationsBroker, dispatcher)
+	watcherDone := make(chan struct{})
+	go func() {
+		defer close(watcherDone)
+		delegationsWatcher.Start(ctx)
+	}()
 
-	api.StartServer(cfg, db)
+	if err := api.StartServer(ctx, cfg, db, delegationsBroker); err != nil {
+		log.Errorf("Server error: %v", err)
+	}
+	// the servers may stop on their own, make sure the watcher stops too
+	stop()
+
+	<-watcherDone
+	<-dispatcherDone
+	if err := db.Close(); err != nil {
+		log.Errorf("Failed to close database: %v", err)
+	}
+	log.Info("Shutdown complete")
 }
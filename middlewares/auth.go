@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuthHandler only lets through the requests bearing the admin token
+func AdminAuthHandler(token string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		bearer, found := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,110 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+var registerFormats sync.Once
+
+// addFormats registers the string formats of the specification, an empty string is valid like an absent parameter
+func addFormats() {
+	formats := map[string]func(string) error{
+		"tezos-address": func(value string) error {
+			if !utils.IsValidAddress(value) {
+				return errors.New("not a valid tz1, tz2, tz3 or KT1 address")
+			}
+			return nil
+		},
+		"cursor": func(value string) error {
+			_, _, err := utils.DecodeCursor(value)
+			return err
+		},
+		"leaderboard-cursor": func(value string) error {
+			_, address, err := utils.DecodeLeaderboardCursor(value)
+			if err == nil && !utils.IsValidAddress(address) {
+				err = errors.New("invalid address")
+			}
+			return err
+		},
+		"time-bound": func(value string) error {
+			if _, err := parseTimeBound(value); err != nil {
+				return errors.New("not a date (2006-01-02) or a RFC 3339 time")
+			}
+			return nil
+		},
+	}
+	for name, validate := range formats {
+		openapi3.DefineStringFormatValidator(name, openapi3.NewCallbackValidator(func(value string) error {
+			if value == "" {
+				return nil
+			}
+			return validate(value)
+		}))
+	}
+}
+
+// OpenAPIValidationHandler checks the path parameters, the query parameters and the body of the request against
+// the operation of the specification matching the route
+func OpenAPIValidationHandler(spec *openapi3.T) gin.HandlerFunc {
+	registerFormats.Do(addFormats)
+	options := &openapi3filter.Options{
+		// the admin token is checked by AdminAuthHandler
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		// the defaults are applied by the handlers, the request is left as it was sent
+		SkipSettingDefaults: true,
+	}
+	options.WithCustomSchemaErrorFunc(func(err *openapi3.SchemaError) string {
+		return err.Reason
+	})
+
+	return func(ctx *gin.Context) {
+		// gin writes the path parameters :name, the specification {name}
+		path := ctx.FullPath()
+		pathParams := make(map[string]string, len(ctx.Params))
+		for _, param := range ctx.Params {
+			path = strings.Replace(path, ":"+param.Key, "{"+param.Key+"}", 1)
+			pathParams[param.Key] = param.Value
+		}
+		pathItem := spec.Paths.Value(path)
+		var operation *openapi3.Operation
+		if pathItem != nil {
+			operation = pathItem.GetOperation(ctx.Request.Method)
+		}
+		if operation == nil {
+			log.Errorf("%s %s is missing from the OpenAPI specification", ctx.Request.Method, path)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			ctx.Abort()
+			return
+		}
+
+		err := openapi3filter.ValidateRequest(ctx.Request.Context(), &openapi3filter.RequestValidationInput{
+			Request:    ctx.Request,
+			PathParams: pathParams,
+			Route: &routers.Route{
+				Spec:      spec,
+				Path:      path,
+				PathItem:  pathItem,
+				Method:    ctx.Request.Method,
+				Operation: operation,
+			},
+			Options: options,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
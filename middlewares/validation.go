@@ -3,29 +3,275 @@ package middlewares
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ibraheemacara/tezos-delegation-service/broker"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	"github.com/ibraheemacara/tezos-delegation-service/utils"
 )
 
+const (
+	DefaultLimit = 50
+	MaxLimit     = 1000
+)
+
+// ValidationHandler sets the year, the filters and the pagination of the delegations to the context. Each parameter
+// is checked against the OpenAPI specification by OpenAPIValidationHandler, only the checks between parameters are left here.
 func ValidationHandler() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		fmt.Println("Validation handler")
-		// get option param from requests and check if it is a valid year & after 2018
-		year := ctx.Param("year")
-		fmt.Println(year)
-		if year != "" {
+		var filter db.DelegationsFilter
+		if year := ctx.Param("year"); year != "" {
 			yearInt, err := strconv.Atoi(year)
-			fmt.Println(year)
-			if err != nil || yearInt < 2018 {
-				fmt.Println("Year must be a valid integer after 2018")
-				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Year must be a valid integer after 2018"})
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "year is invalid"})
 				ctx.Abort()
 				return
 			}
-			//set the year param to the context
-			ctx.Set("year", yearInt)
+			filter.Year = yearInt
+		}
+		if !parseDelegationsFilter(ctx, &filter) {
+			return
+		}
+		ctx.Set("filter", filter)
+
+		// pagination params, limit defaults to DefaultLimit
+		limit, ok := parseQuery(ctx, "limit", strconv.Atoi)
+		if !ok {
+			return
+		}
+		if limit != nil {
+			ctx.Set("limit", *limit)
+		} else {
+			ctx.Set("limit", DefaultLimit)
+		}
+
+		cursor, ok := parseQuery(ctx, "cursor", func(value string) (db.Cursor, error) {
+			block, id, err := utils.DecodeCursor(value)
+			return db.Cursor{Block: block, ID: id}, err
+		})
+		if !ok {
+			return
+		}
+		if cursor != nil {
+			ctx.Set("cursor", cursor)
+		}
+
+		ctx.Next()
+	}
+}
+
+// parseQuery parses the query parameter name, nil when it is absent. The parameters are checked against the OpenAPI
+// specification beforehand, a value that can't be parsed all the same is answered with a 400.
+func parseQuery[T any](ctx *gin.Context, name string, parse func(string) (T, error)) (*T, bool) {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := parse(value)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s is invalid", name)})
+		ctx.Abort()
+		return nil, false
+	}
+	return &parsed, true
+}
+
+// parseDelegationsFilter reads the time, block and amount ranges of the delegations. A range ending before it starts,
+// or a time range outside the year, is answered with a 400.
+func parseDelegationsFilter(ctx *gin.Context, filter *db.DelegationsFilter) bool {
+	from, ok := parseQuery(ctx, "from", parseTimeBound)
+	if !ok {
+		return false
+	}
+	to, ok := parseQuery(ctx, "to", parseTimeBound)
+	if !ok {
+		return false
+	}
+	if from != nil {
+		filter.From = *from
+	}
+	if to != nil {
+		filter.To = *to
+	}
+	if filter.BlockFrom, ok = parseQuery(ctx, "block_from", parseBlock); !ok {
+		return false
+	}
+	if filter.BlockTo, ok = parseQuery(ctx, "block_to", parseBlock); !ok {
+		return false
+	}
+	if filter.MinAmount, ok = parseQuery(ctx, "min_amount", parseAmount); !ok {
+		return false
+	}
+	if filter.MaxAmount, ok = parseQuery(ctx, "max_amount", parseAmount); !ok {
+		return false
+	}
+
+	if err := filter.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Abort()
+		return false
+	}
+	return true
+}
+
+func parseBlock(value string) (int32, error) {
+	block, err := strconv.ParseInt(value, 10, 32)
+	return int32(block), err
+}
+
+func parseAmount(value string) (int64, error) {
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// StreamValidationHandler sets the filters of the delegations stream to the context
+func StreamValidationHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		filter := broker.Filter{Delegator: ctx.Query("delegator"), Baker: ctx.Query("baker")}
+		minAmount, ok := parseQuery(ctx, "min_amount", parseAmount)
+		if !ok {
+			return
+		}
+		if minAmount != nil {
+			filter.MinAmount = *minAmount
+		}
+		ctx.Set("filter", filter)
+
+		ctx.Next()
+	}
+}
+
+// WebhookValidationHandler sets the webhook id of the path and the webhook of the body, if any, to the context. The url
+// is the only field the OpenAPI specification leaves to it.
+func WebhookValidationHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if idStr := ctx.Param("id"); idStr != "" {
+			id, err := strconv.ParseUint(idStr, 10, 32)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Webhook id must be a positive integer"})
+				ctx.Abort()
+				return
+			}
+			ctx.Set("id", uint(id))
+		}
+
+		if ctx.Request.Method != http.MethodPost && ctx.Request.Method != http.MethodPut {
+			ctx.Next()
+			return
+		}
+		var request types.WebhookRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Body must be a valid webhook"})
+			ctx.Abort()
+			return
+		}
+		if target, err := url.Parse(request.Url); err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute http or https url"})
+			ctx.Abort()
+			return
+		}
+		ctx.Set("webhook", request)
+
+		ctx.Next()
+	}
+}
+
+// StatsValidationHandler sets the interval, the grouping and the time bounds of the stats to the context
+func StatsValidationHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		query := db.StatsQuery{Interval: db.StatsIntervalMonth}
+		if interval := ctx.Query("interval"); interval != "" {
+			query.Interval = interval
+		}
+		byBaker, ok := parseQuery(ctx, "by_baker", strconv.ParseBool)
+		if !ok {
+			return
+		}
+		if byBaker != nil {
+			query.ByBaker = *byBaker
+		}
+		if !parseTimeRange(ctx, parseTimeBound, &query.From, &query.To) {
+			return
+		}
+		ctx.Set("stats", query)
+
+		ctx.Next()
+	}
+}
+
+// parseTimeRange reads the from and to query parameters, zero when absent. A range ending before it starts is answered with a 400.
+func parseTimeRange(ctx *gin.Context, parse func(string) (time.Time, error), from, to *time.Time) bool {
+	for _, param := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", from}, {"to", to}} {
+		bound, ok := parseQuery(ctx, param.name, parse)
+		if !ok {
+			return false
+		}
+		if bound != nil {
+			*param.value = *bound
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(*to) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		ctx.Abort()
+		return false
+	}
+	return true
+}
+
+// parseTimeBound reads a RFC 3339 time or a date, a date is the start of the day in UTC
+func parseTimeBound(value string) (time.Time, error) {
+	if bound, err := time.Parse(time.DateOnly, value); err == nil {
+		return bound, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// LeaderboardValidationHandler sets the year, the ranking, the days and the pagination of a leaderboard to the context
+func LeaderboardValidationHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		query := db.LeaderboardQuery{OrderBy: db.LeaderboardByAmount, Limit: DefaultLimit}
+		if year := ctx.Param("year"); year != "" {
+			yearInt, err := strconv.Atoi(year)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "year is invalid"})
+				ctx.Abort()
+				return
+			}
+			query.Year = yearInt
+		}
+		if by := ctx.Query("by"); by != "" {
+			query.OrderBy = by
+		}
+
+		// the leaderboards are summed by day, the bounds are days
+		parseDay := func(value string) (time.Time, error) { return time.Parse(time.DateOnly, value) }
+		if !parseTimeRange(ctx, parseDay, &query.From, &query.To) {
+			return
+		}
+
+		limit, ok := parseQuery(ctx, "limit", strconv.Atoi)
+		if !ok {
+			return
+		}
+		if limit != nil {
+			query.Limit = *limit
+		}
+
+		cursor, ok := parseQuery(ctx, "cursor", func(value string) (db.LeaderboardCursor, error) {
+			cursorValue, address, err := utils.DecodeLeaderboardCursor(value)
+			return db.LeaderboardCursor{Value: cursorValue, Address: address}, err
+		})
+		if !ok {
+			return
 		}
+		query.Cursor = cursor
+		ctx.Set("leaderboard", query)
 
 		ctx.Next()
 	}
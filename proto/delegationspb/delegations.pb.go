@@ -0,0 +1,589 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: delegations.proto
+
+package delegationspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Delegation struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Delegator string                 `protobuf:"bytes,1,opt,name=delegator,proto3" json:"delegator,omitempty"`
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Block     int32                  `protobuf:"varint,3,opt,name=block,proto3" json:"block,omitempty"`
+	Amount    int64                  `protobuf:"varint,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	// empty when the delegator removed its delegate
+	NewDelegate   string `protobuf:"bytes,5,opt,name=new_delegate,json=newDelegate,proto3" json:"new_delegate,omitempty"`
+	PrevDelegate  string `protobuf:"bytes,6,opt,name=prev_delegate,json=prevDelegate,proto3" json:"prev_delegate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Delegation) Reset() {
+	*x = Delegation{}
+	mi := &file_delegations_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Delegation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Delegation) ProtoMessage() {}
+
+func (x *Delegation) ProtoReflect() protoreflect.Message {
+	mi := &file_delegations_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Delegation.ProtoReflect.Descriptor instead.
+func (*Delegation) Descriptor() ([]byte, []int) {
+	return file_delegations_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Delegation) GetDelegator() string {
+	if x != nil {
+		return x.Delegator
+	}
+	return ""
+}
+
+func (x *Delegation) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Delegation) GetBlock() int32 {
+	if x != nil {
+		return x.Block
+	}
+	return 0
+}
+
+func (x *Delegation) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Delegation) GetNewDelegate() string {
+	if x != nil {
+		return x.NewDelegate
+	}
+	return ""
+}
+
+func (x *Delegation) GetPrevDelegate() string {
+	if x != nil {
+		return x.PrevDelegate
+	}
+	return ""
+}
+
+type ListDelegationsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// keeps the delegations of the year in UTC
+	Year *int32 `protobuf:"varint,1,opt,name=year,proto3,oneof" json:"year,omitempty"`
+	// keep the delegations of [from, to[
+	From *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	// keep the delegations of the blocks [block_from, block_to]
+	BlockFrom *int32 `protobuf:"varint,4,opt,name=block_from,json=blockFrom,proto3,oneof" json:"block_from,omitempty"`
+	BlockTo   *int32 `protobuf:"varint,5,opt,name=block_to,json=blockTo,proto3,oneof" json:"block_to,omitempty"`
+	// keep the delegations whose amount is in [min_amount, max_amount]
+	MinAmount *int64 `protobuf:"varint,6,opt,name=min_amount,json=minAmount,proto3,oneof" json:"min_amount,omitempty"`
+	MaxAmount *int64 `protobuf:"varint,7,opt,name=max_amount,json=maxAmount,proto3,oneof" json:"max_amount,omitempty"`
+	// delegations per page, between 1 and 1000, 50 when unset
+	Limit int32 `protobuf:"varint,8,opt,name=limit,proto3" json:"limit,omitempty"`
+	// next_cursor of the previous page
+	Cursor        string `protobuf:"bytes,9,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDelegationsRequest) Reset() {
+	*x = ListDelegationsRequest{}
+	mi := &file_delegations_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDelegationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDelegationsRequest) ProtoMessage() {}
+
+func (x *ListDelegationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_delegations_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDelegationsRequest.ProtoReflect.Descriptor instead.
+func (*ListDelegationsRequest) Descriptor() ([]byte, []int) {
+	return file_delegations_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListDelegationsRequest) GetYear() int32 {
+	if x != nil && x.Year != nil {
+		return *x.Year
+	}
+	return 0
+}
+
+func (x *ListDelegationsRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *ListDelegationsRequest) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *ListDelegationsRequest) GetBlockFrom() int32 {
+	if x != nil && x.BlockFrom != nil {
+		return *x.BlockFrom
+	}
+	return 0
+}
+
+func (x *ListDelegationsRequest) GetBlockTo() int32 {
+	if x != nil && x.BlockTo != nil {
+		return *x.BlockTo
+	}
+	return 0
+}
+
+func (x *ListDelegationsRequest) GetMinAmount() int64 {
+	if x != nil && x.MinAmount != nil {
+		return *x.MinAmount
+	}
+	return 0
+}
+
+func (x *ListDelegationsRequest) GetMaxAmount() int64 {
+	if x != nil && x.MaxAmount != nil {
+		return *x.MaxAmount
+	}
+	return 0
+}
+
+func (x *ListDelegationsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListDelegationsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type ListDelegationsResponse struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Delegations []*Delegation          `protobuf:"bytes,1,rep,name=delegations,proto3" json:"delegations,omitempty"`
+	// empty on the last page
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDelegationsResponse) Reset() {
+	*x = ListDelegationsResponse{}
+	mi := &file_delegations_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDelegationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDelegationsResponse) ProtoMessage() {}
+
+func (x *ListDelegationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_delegations_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDelegationsResponse.ProtoReflect.Descriptor instead.
+func (*ListDelegationsResponse) Descriptor() ([]byte, []int) {
+	return file_delegations_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListDelegationsResponse) GetDelegations() []*Delegation {
+	if x != nil {
+		return x.Delegations
+	}
+	return nil
+}
+
+func (x *ListDelegationsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type GetDelegatorRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDelegatorRequest) Reset() {
+	*x = GetDelegatorRequest{}
+	mi := &file_delegations_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDelegatorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDelegatorRequest) ProtoMessage() {}
+
+func (x *GetDelegatorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_delegations_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDelegatorRequest.ProtoReflect.Descriptor instead.
+func (*GetDelegatorRequest) Descriptor() ([]byte, []int) {
+	return file_delegations_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetDelegatorRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type DelegatorTimeline struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Delegator string                 `protobuf:"bytes,1,opt,name=delegator,proto3" json:"delegator,omitempty"`
+	// delegate set by the newest delegation, empty when it removed the delegate
+	CurrentDelegate string `protobuf:"bytes,2,opt,name=current_delegate,json=currentDelegate,proto3" json:"current_delegate,omitempty"`
+	// when the delegator started delegating to its current delegate, unset without a current delegate
+	DelegatingSince *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=delegating_since,json=delegatingSince,proto3" json:"delegating_since,omitempty"`
+	SinceBlock      int32                  `protobuf:"varint,4,opt,name=since_block,json=sinceBlock,proto3" json:"since_block,omitempty"`
+	// balance of the delegator when it started delegating to its current delegate
+	Amount int64 `protobuf:"varint,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	// delegations from the oldest to the newest
+	History       []*Delegation `protobuf:"bytes,6,rep,name=history,proto3" json:"history,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DelegatorTimeline) Reset() {
+	*x = DelegatorTimeline{}
+	mi := &file_delegations_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DelegatorTimeline) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DelegatorTimeline) ProtoMessage() {}
+
+func (x *DelegatorTimeline) ProtoReflect() protoreflect.Message {
+	mi := &file_delegations_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DelegatorTimeline.ProtoReflect.Descriptor instead.
+func (*DelegatorTimeline) Descriptor() ([]byte, []int) {
+	return file_delegations_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DelegatorTimeline) GetDelegator() string {
+	if x != nil {
+		return x.Delegator
+	}
+	return ""
+}
+
+func (x *DelegatorTimeline) GetCurrentDelegate() string {
+	if x != nil {
+		return x.CurrentDelegate
+	}
+	return ""
+}
+
+func (x *DelegatorTimeline) GetDelegatingSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DelegatingSince
+	}
+	return nil
+}
+
+func (x *DelegatorTimeline) GetSinceBlock() int32 {
+	if x != nil {
+		return x.SinceBlock
+	}
+	return 0
+}
+
+func (x *DelegatorTimeline) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *DelegatorTimeline) GetHistory() []*Delegation {
+	if x != nil {
+		return x.History
+	}
+	return nil
+}
+
+type WatchDelegationsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Delegator     string                 `protobuf:"bytes,1,opt,name=delegator,proto3" json:"delegator,omitempty"`
+	Baker         string                 `protobuf:"bytes,2,opt,name=baker,proto3" json:"baker,omitempty"`
+	MinAmount     int64                  `protobuf:"varint,3,opt,name=min_amount,json=minAmount,proto3" json:"min_amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchDelegationsRequest) Reset() {
+	*x = WatchDelegationsRequest{}
+	mi := &file_delegations_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchDelegationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchDelegationsRequest) ProtoMessage() {}
+
+func (x *WatchDelegationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_delegations_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchDelegationsRequest.ProtoReflect.Descriptor instead.
+func (*WatchDelegationsRequest) Descriptor() ([]byte, []int) {
+	return file_delegations_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WatchDelegationsRequest) GetDelegator() string {
+	if x != nil {
+		return x.Delegator
+	}
+	return ""
+}
+
+func (x *WatchDelegationsRequest) GetBaker() string {
+	if x != nil {
+		return x.Baker
+	}
+	return ""
+}
+
+func (x *WatchDelegationsRequest) GetMinAmount() int64 {
+	if x != nil {
+		return x.MinAmount
+	}
+	return 0
+}
+
+var File_delegations_proto protoreflect.FileDescriptor
+
+const file_delegations_proto_rawDesc = "" +
+	"\n" +
+	"\x11delegations.proto\x12\x0edelegations.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xda\x01\n" +
+	"\n" +
+	"Delegation\x12\x1c\n" +
+	"\tdelegator\x18\x01 \x01(\tR\tdelegator\x128\n" +
+	"\ttimestamp\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x14\n" +
+	"\x05block\x18\x03 \x01(\x05R\x05block\x12\x16\n" +
+	"\x06amount\x18\x04 \x01(\x03R\x06amount\x12!\n" +
+	"\fnew_delegate\x18\x05 \x01(\tR\vnewDelegate\x12#\n" +
+	"\rprev_delegate\x18\x06 \x01(\tR\fprevDelegate\"\x8a\x03\n" +
+	"\x16ListDelegationsRequest\x12\x17\n" +
+	"\x04year\x18\x01 \x01(\x05H\x00R\x04year\x88\x01\x01\x12.\n" +
+	"\x04from\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\x12\"\n" +
+	"\n" +
+	"block_from\x18\x04 \x01(\x05H\x01R\tblockFrom\x88\x01\x01\x12\x1e\n" +
+	"\bblock_to\x18\x05 \x01(\x05H\x02R\ablockTo\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"min_amount\x18\x06 \x01(\x03H\x03R\tminAmount\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"max_amount\x18\a \x01(\x03H\x04R\tmaxAmount\x88\x01\x01\x12\x14\n" +
+	"\x05limit\x18\b \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06cursor\x18\t \x01(\tR\x06cursorB\a\n" +
+	"\x05_yearB\r\n" +
+	"\v_block_fromB\v\n" +
+	"\t_block_toB\r\n" +
+	"\v_min_amountB\r\n" +
+	"\v_max_amount\"x\n" +
+	"\x17ListDelegationsResponse\x12<\n" +
+	"\vdelegations\x18\x01 \x03(\v2\x1a.delegations.v1.DelegationR\vdelegations\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"/\n" +
+	"\x13GetDelegatorRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\"\x92\x02\n" +
+	"\x11DelegatorTimeline\x12\x1c\n" +
+	"\tdelegator\x18\x01 \x01(\tR\tdelegator\x12)\n" +
+	"\x10current_delegate\x18\x02 \x01(\tR\x0fcurrentDelegate\x12E\n" +
+	"\x10delegating_since\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x0fdelegatingSince\x12\x1f\n" +
+	"\vsince_block\x18\x04 \x01(\x05R\n" +
+	"sinceBlock\x12\x16\n" +
+	"\x06amount\x18\x05 \x01(\x03R\x06amount\x124\n" +
+	"\ahistory\x18\x06 \x03(\v2\x1a.delegations.v1.DelegationR\ahistory\"l\n" +
+	"\x17WatchDelegationsRequest\x12\x1c\n" +
+	"\tdelegator\x18\x01 \x01(\tR\tdelegator\x12\x14\n" +
+	"\x05baker\x18\x02 \x01(\tR\x05baker\x12\x1d\n" +
+	"\n" +
+	"min_amount\x18\x03 \x01(\x03R\tminAmount2\xab\x02\n" +
+	"\x12DelegationsService\x12b\n" +
+	"\x0fListDelegations\x12&.delegations.v1.ListDelegationsRequest\x1a'.delegations.v1.ListDelegationsResponse\x12V\n" +
+	"\fGetDelegator\x12#.delegations.v1.GetDelegatorRequest\x1a!.delegations.v1.DelegatorTimeline\x12Y\n" +
+	"\x10WatchDelegations\x12'.delegations.v1.WatchDelegationsRequest\x1a\x1a.delegations.v1.Delegation0\x01BGZEgithub.com/ibraheemacara/tezos-delegation-service/proto/delegationspbb\x06proto3"
+
+var (
+	file_delegations_proto_rawDescOnce sync.Once
+	file_delegations_proto_rawDescData []byte
+)
+
+func file_delegations_proto_rawDescGZIP() []byte {
+	file_delegations_proto_rawDescOnce.Do(func() {
+		file_delegations_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_delegations_proto_rawDesc), len(file_delegations_proto_rawDesc)))
+	})
+	return file_delegations_proto_rawDescData
+}
+
+var file_delegations_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_delegations_proto_goTypes = []any{
+	(*Delegation)(nil),              // 0: delegations.v1.Delegation
+	(*ListDelegationsRequest)(nil),  // 1: delegations.v1.ListDelegationsRequest
+	(*ListDelegationsResponse)(nil), // 2: delegations.v1.ListDelegationsResponse
+	(*GetDelegatorRequest)(nil),     // 3: delegations.v1.GetDelegatorRequest
+	(*DelegatorTimeline)(nil),       // 4: delegations.v1.DelegatorTimeline
+	(*WatchDelegationsRequest)(nil), // 5: delegations.v1.WatchDelegationsRequest
+	(*timestamppb.Timestamp)(nil),   // 6: google.protobuf.Timestamp
+}
+var file_delegations_proto_depIdxs = []int32{
+	6, // 0: delegations.v1.Delegation.timestamp:type_name -> google.protobuf.Timestamp
+	6, // 1: delegations.v1.ListDelegationsRequest.from:type_name -> google.protobuf.Timestamp
+	6, // 2: delegations.v1.ListDelegationsRequest.to:type_name -> google.protobuf.Timestamp
+	0, // 3: delegations.v1.ListDelegationsResponse.delegations:type_name -> delegations.v1.Delegation
+	6, // 4: delegations.v1.DelegatorTimeline.delegating_since:type_name -> google.protobuf.Timestamp
+	0, // 5: delegations.v1.DelegatorTimeline.history:type_name -> delegations.v1.Delegation
+	1, // 6: delegations.v1.DelegationsService.ListDelegations:input_type -> delegations.v1.ListDelegationsRequest
+	3, // 7: delegations.v1.DelegationsService.GetDelegator:input_type -> delegations.v1.GetDelegatorRequest
+	5, // 8: delegations.v1.DelegationsService.WatchDelegations:input_type -> delegations.v1.WatchDelegationsRequest
+	2, // 9: delegations.v1.DelegationsService.ListDelegations:output_type -> delegations.v1.ListDelegationsResponse
+	4, // 10: delegations.v1.DelegationsService.GetDelegator:output_type -> delegations.v1.DelegatorTimeline
+	0, // 11: delegations.v1.DelegationsService.WatchDelegations:output_type -> delegations.v1.Delegation
+	9, // [9:12] is the sub-list for method output_type
+	6, // [6:9] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_delegations_proto_init() }
+func file_delegations_proto_init() {
+	if File_delegations_proto != nil {
+		return
+	}
+	file_delegations_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_delegations_proto_rawDesc), len(file_delegations_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_delegations_proto_goTypes,
+		DependencyIndexes: file_delegations_proto_depIdxs,
+		MessageInfos:      file_delegations_proto_msgTypes,
+	}.Build()
+	File_delegations_proto = out.File
+	file_delegations_proto_goTypes = nil
+	file_delegations_proto_depIdxs = nil
+}
@@ -0,0 +1,213 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: delegations.proto
+
+package delegationspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	DelegationsService_ListDelegations_FullMethodName  = "/delegations.v1.DelegationsService/ListDelegations"
+	DelegationsService_GetDelegator_FullMethodName     = "/delegations.v1.DelegationsService/GetDelegator"
+	DelegationsService_WatchDelegations_FullMethodName = "/delegations.v1.DelegationsService/WatchDelegations"
+)
+
+// DelegationsServiceClient is the client API for DelegationsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// DelegationsService serves the delegations like the REST api. Amounts are in mutez, an empty address means none.
+type DelegationsServiceClient interface {
+	// ListDelegations returns a page of the delegations matching the filters, from the newest to the oldest
+	ListDelegations(ctx context.Context, in *ListDelegationsRequest, opts ...grpc.CallOption) (*ListDelegationsResponse, error)
+	// GetDelegator returns the current delegate and the history of a delegator, NOT_FOUND when it never delegated
+	GetDelegator(ctx context.Context, in *GetDelegatorRequest, opts ...grpc.CallOption) (*DelegatorTimeline, error)
+	// WatchDelegations streams the delegations matching the filters once they are stored. A client too slow to keep
+	// up gets UNAVAILABLE, so does every client when the server stops.
+	WatchDelegations(ctx context.Context, in *WatchDelegationsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Delegation], error)
+}
+
+type delegationsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDelegationsServiceClient(cc grpc.ClientConnInterface) DelegationsServiceClient {
+	return &delegationsServiceClient{cc}
+}
+
+func (c *delegationsServiceClient) ListDelegations(ctx context.Context, in *ListDelegationsRequest, opts ...grpc.CallOption) (*ListDelegationsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDelegationsResponse)
+	err := c.cc.Invoke(ctx, DelegationsService_ListDelegations_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *delegationsServiceClient) GetDelegator(ctx context.Context, in *GetDelegatorRequest, opts ...grpc.CallOption) (*DelegatorTimeline, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DelegatorTimeline)
+	err := c.cc.Invoke(ctx, DelegationsService_GetDelegator_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *delegationsServiceClient) WatchDelegations(ctx context.Context, in *WatchDelegationsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Delegation], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DelegationsService_ServiceDesc.Streams[0], DelegationsService_WatchDelegations_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchDelegationsRequest, Delegation]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DelegationsService_WatchDelegationsClient = grpc.ServerStreamingClient[Delegation]
+
+// DelegationsServiceServer is the server API for DelegationsService service.
+// All implementations must embed UnimplementedDelegationsServiceServer
+// for forward compatibility.
+//
+// DelegationsService serves the delegations like the REST api. Amounts are in mutez, an empty address means none.
+type DelegationsServiceServer interface {
+	// ListDelegations returns a page of the delegations matching the filters, from the newest to the oldest
+	ListDelegations(context.Context, *ListDelegationsRequest) (*ListDelegationsResponse, error)
+	// GetDelegator returns the current delegate and the history of a delegator, NOT_FOUND when it never delegated
+	GetDelegator(context.Context, *GetDelegatorRequest) (*DelegatorTimeline, error)
+	// WatchDelegations streams the delegations matching the filters once they are stored. A client too slow to keep
+	// up gets UNAVAILABLE, so does every client when the server stops.
+	WatchDelegations(*WatchDelegationsRequest, grpc.ServerStreamingServer[Delegation]) error
+	mustEmbedUnimplementedDelegationsServiceServer()
+}
+
+// UnimplementedDelegationsServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDelegationsServiceServer struct{}
+
+func (UnimplementedDelegationsServiceServer) ListDelegations(context.Context, *ListDelegationsRequest) (*ListDelegationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDelegations not implemented")
+}
+func (UnimplementedDelegationsServiceServer) GetDelegator(context.Context, *GetDelegatorRequest) (*DelegatorTimeline, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDelegator not implemented")
+}
+func (UnimplementedDelegationsServiceServer) WatchDelegations(*WatchDelegationsRequest, grpc.ServerStreamingServer[Delegation]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchDelegations not implemented")
+}
+func (UnimplementedDelegationsServiceServer) mustEmbedUnimplementedDelegationsServiceServer() {}
+func (UnimplementedDelegationsServiceServer) testEmbeddedByValue()                            {}
+
+// UnsafeDelegationsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DelegationsServiceServer will
+// result in compilation errors.
+type UnsafeDelegationsServiceServer interface {
+	mustEmbedUnimplementedDelegationsServiceServer()
+}
+
+func RegisterDelegationsServiceServer(s grpc.ServiceRegistrar, srv DelegationsServiceServer) {
+	// If the following call pancis, it indicates UnimplementedDelegationsServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&DelegationsService_ServiceDesc, srv)
+}
+
+func _DelegationsService_ListDelegations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDelegationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DelegationsServiceServer).ListDelegations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DelegationsService_ListDelegations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DelegationsServiceServer).ListDelegations(ctx, req.(*ListDelegationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DelegationsService_GetDelegator_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDelegatorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DelegationsServiceServer).GetDelegator(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DelegationsService_GetDelegator_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DelegationsServiceServer).GetDelegator(ctx, req.(*GetDelegatorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DelegationsService_WatchDelegations_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchDelegationsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DelegationsServiceServer).WatchDelegations(m, &grpc.GenericServerStream[WatchDelegationsRequest, Delegation]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DelegationsService_WatchDelegationsServer = grpc.ServerStreamingServer[Delegation]
+
+// DelegationsService_ServiceDesc is the grpc.ServiceDesc for DelegationsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DelegationsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "delegations.v1.DelegationsService",
+	HandlerType: (*DelegationsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListDelegations",
+			Handler:    _DelegationsService_ListDelegations_Handler,
+		},
+		{
+			MethodName: "GetDelegator",
+			Handler:    _DelegationsService_GetDelegator_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchDelegations",
+			Handler:       _DelegationsService_WatchDelegations_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "delegations.proto",
+}
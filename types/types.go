@@ -3,10 +3,14 @@ package types
 import "time"
 
 type TzktDelegationsResponse struct {
-	Level     int32     `json:"level"`
-	Timestamp time.Time `json:"timestamp"`
-	Sender    Address   `json:"sender"`
-	Amount    int64     `json:"amount"`
+	Id           int64     `json:"id"`
+	Hash         string    `json:"hash"`
+	Level        int32     `json:"level"`
+	Timestamp    time.Time `json:"timestamp"`
+	Sender       Address   `json:"sender"`
+	Amount       int64     `json:"amount"`
+	NewDelegate  *Address  `json:"newDelegate"`
+	PrevDelegate *Address  `json:"prevDelegate"`
 }
 
 type Address struct {
@@ -14,12 +18,74 @@ type Address struct {
 }
 
 type Delegation struct {
-	Delegator string    `json:"delegator"`
-	Timestamp time.Time `json:"timestamp"`
-	Block     int32     `json:"block"`
-	Amount    int64     `json:"amount"`
+	Delegator    string    `json:"delegator"`
+	Timestamp    time.Time `json:"timestamp"`
+	Block        int32     `json:"block"`
+	Amount       int64     `json:"amount"`
+	NewDelegate  string    `json:"new_delegate"`
+	PrevDelegate string    `json:"prev_delegate"`
 }
 
 type DelegationsResponse struct {
 	Delegations []Delegation `json:"data"`
+	NextCursor  string       `json:"next_cursor,omitempty"`
+}
+
+// DelegationStats aggregates the delegations of a period, and of a baker when grouped by baker
+type DelegationStats struct {
+	// Period is the year (2024), the month (2024-03) or the day (2024-03-15) of the delegations
+	Period           string  `json:"period"`
+	Baker            *string `json:"baker,omitempty"`
+	Count            int64   `json:"count"`
+	TotalAmount      int64   `json:"total_amount"`
+	AverageAmount    float64 `json:"average_amount"`
+	UniqueDelegators int64   `json:"unique_delegators"`
+}
+
+type DelegationStatsResponse struct {
+	Interval string            `json:"interval"`
+	Stats    []DelegationStats `json:"data"`
+}
+
+// LeaderboardEntry totals the delegations of a delegator, or the delegations to a baker
+type LeaderboardEntry struct {
+	Address     string `json:"address"`
+	Count       int64  `json:"count"`
+	TotalAmount int64  `json:"total_amount"`
+}
+
+type LeaderboardResponse struct {
+	Entries    []LeaderboardEntry `json:"data"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// DelegatorTimeline is the history of the delegations of a delegator and its current delegate
+type DelegatorTimeline struct {
+	Delegator string `json:"delegator"`
+	// CurrentDelegate is the delegate set by the newest delegation, empty when it removed its delegate
+	CurrentDelegate string `json:"current_delegate"`
+	// DelegatingSince and SinceBlock tell when the delegator started delegating to its current delegate
+	DelegatingSince *time.Time `json:"delegating_since,omitempty"`
+	SinceBlock      int32      `json:"since_block,omitempty"`
+	// Amount is the balance of the delegator when it started delegating to its current delegate
+	Amount  int64        `json:"amount"`
+	History []Delegation `json:"history"`
+}
+
+// BakerDelegator is a delegator currently delegating to a baker
+type BakerDelegator struct {
+	Delegator string `json:"delegator"`
+	// Amount is the balance of the delegator when it started delegating to the baker
+	Amount          int64     `json:"amount"`
+	DelegatingSince time.Time `json:"delegating_since"`
+	SinceBlock      int32     `json:"since_block"`
+}
+
+// BakerDelegatorsResponse is a page of the current delegators of a baker and the totals over all of them
+type BakerDelegatorsResponse struct {
+	Baker           string           `json:"baker"`
+	DelegatorsCount int64            `json:"delegators_count"`
+	TotalAmount     int64            `json:"total_amount"`
+	Delegators      []BakerDelegator `json:"data"`
+	NextCursor      string           `json:"next_cursor,omitempty"`
 }
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook is a webhook returned by the admin api, the secret is only returned when it is created
+type Webhook struct {
+	ID        uint   `json:"id"`
+	Url       string `json:"url"`
+	Secret    string `json:"secret,omitempty"`
+	Delegator string `json:"delegator,omitempty"`
+	Baker     string `json:"baker,omitempty"`
+	MinAmount int64  `json:"min_amount,omitempty"`
+	Active    bool   `json:"active"`
+}
+
+// WebhookRequest creates or replaces a webhook, a secret is generated when none is given
+type WebhookRequest struct {
+	Url       string `json:"url"`
+	Secret    string `json:"secret"`
+	Delegator string `json:"delegator"`
+	Baker     string `json:"baker"`
+	MinAmount int64  `json:"min_amount"`
+	// defaults to true
+	Active *bool `json:"active"`
+}
+
+type WebhookDeadLetter struct {
+	ID        uint            `json:"id"`
+	WebhookID uint            `json:"webhook_id"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	Error     string          `json:"error"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// WebhookPayload is the body posted to a webhook with the new delegations matching its filters
+type WebhookPayload struct {
+	WebhookID   uint         `json:"webhook_id"`
+	Delegations []Delegation `json:"delegations"`
+}
@@ -1,6 +1,16 @@
 package utils
 
-import "time"
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
 
 func GetYearFromTimestamp(timestamp string) (string, error) {
 	time, err := time.Parse(time.RFC3339, timestamp)
@@ -9,3 +19,105 @@ func GetYearFromTimestamp(timestamp string) (string, error) {
 	}
 	return time.Format("2006"), nil
 }
+
+// EncodeCursor builds the opaque pagination cursor pointing at a delegation row.
+func EncodeCursor(block int32, id uint) string {
+	raw := fmt.Sprintf("%d:%d", block, id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor returns the block and id encoded by EncodeCursor.
+func DecodeCursor(cursor string) (int32, uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, errors.New("invalid cursor")
+	}
+	blockStr, idStr, found := strings.Cut(string(raw), ":")
+	if !found {
+		return 0, 0, errors.New("invalid cursor")
+	}
+	block, err := strconv.ParseInt(blockStr, 10, 32)
+	if err != nil {
+		return 0, 0, errors.New("invalid cursor")
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("invalid cursor")
+	}
+	return int32(block), uint(id), nil
+}
+
+// EncodeLeaderboardCursor builds the opaque cursor pointing at the last address of a leaderboard page and its ranked value.
+func EncodeLeaderboardCursor(value int64, address string) string {
+	raw := fmt.Sprintf("%d:%s", value, address)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeLeaderboardCursor returns the value and the address encoded by EncodeLeaderboardCursor.
+func DecodeLeaderboardCursor(cursor string) (int64, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", errors.New("invalid cursor")
+	}
+	valueStr, address, found := strings.Cut(string(raw), ":")
+	if !found || address == "" {
+		return 0, "", errors.New("invalid cursor")
+	}
+	value, err := strconv.ParseInt(valueStr, 10, 64)
+	if err != nil {
+		return 0, "", errors.New("invalid cursor")
+	}
+	return value, address, nil
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// tezosAddressPrefixes maps the address prefixes we accept to their base58check version bytes
+var tezosAddressPrefixes = map[string][]byte{
+	"tz1": {6, 161, 159},
+	"tz2": {6, 161, 161},
+	"tz3": {6, 161, 164},
+	"KT1": {2, 90, 121},
+}
+
+// IsValidAddress checks that address is a tz1, tz2, tz3 or KT1 address with a valid checksum
+func IsValidAddress(address string) bool {
+	if len(address) != 36 {
+		return false
+	}
+	prefix, ok := tezosAddressPrefixes[address[:3]]
+	if !ok {
+		return false
+	}
+	decoded, err := base58Decode(address)
+	// version bytes + 20 bytes hash + 4 bytes checksum
+	if err != nil || len(decoded) != len(prefix)+24 || !bytes.Equal(decoded[:len(prefix)], prefix) {
+		return false
+	}
+	payload, checksum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	return bytes.Equal(second[:4], checksum)
+}
+
+func base58Decode(s string) ([]byte, error) {
+	value := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range s {
+		index := strings.IndexRune(base58Alphabet, c)
+		if index < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		value.Mul(value, radix)
+		value.Add(value, big.NewInt(int64(index)))
+	}
+	decoded := value.Bytes()
+	// leading '1' characters stand for leading zero bytes
+	for _, c := range s {
+		if c != '1' {
+			break
+		}
+		decoded = append([]byte{0}, decoded...)
+	}
+	return decoded, nil
+}
@@ -21,3 +21,71 @@ func TestGetYearFromTimestampWrong(t *testing.T) {
 		t.Fatalf("GetYearFromTimestamp should have failed")
 	}
 }
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := EncodeCursor(109, 42)
+	block, id, err := DecodeCursor(cursor)
+	if err != nil {
+		t.Fatalf("DecodeCursor failed: %v", err)
+	}
+	if block != 109 || id != 42 {
+		t.Errorf("expected block 109 and id 42, got block %d and id %d", block, id)
+	}
+}
+
+func TestDecodeCursorWrong(t *testing.T) {
+	for _, cursor := range []string{"", "not base64!", EncodeCursor(1, 2)[1:], "MTA5"} {
+		if _, _, err := DecodeCursor(cursor); err == nil {
+			t.Errorf("DecodeCursor(%q) should have failed", cursor)
+		}
+	}
+}
+
+func TestLeaderboardCursorRoundTrip(t *testing.T) {
+	cursor := EncodeLeaderboardCursor(1500000, "tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd")
+	value, address, err := DecodeLeaderboardCursor(cursor)
+	if err != nil {
+		t.Fatalf("DecodeLeaderboardCursor failed: %v", err)
+	}
+	if value != 1500000 || address != "tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd" {
+		t.Errorf("expected value 1500000 and address tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd, got %d and %s", value, address)
+	}
+}
+
+func TestDecodeLeaderboardCursorWrong(t *testing.T) {
+	for _, cursor := range []string{"", "not base64!", EncodeCursor(1, 2)[1:], EncodeLeaderboardCursor(1, "")} {
+		if _, _, err := DecodeLeaderboardCursor(cursor); err == nil {
+			t.Errorf("DecodeLeaderboardCursor(%q) should have failed", cursor)
+		}
+	}
+}
+
+func TestIsValidAddress(t *testing.T) {
+	valid := []string{
+		"tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd",
+		"tz2Ch1abG7FNiibmV26Uzgdsnfni9XGrk5wD",
+		"tz3WXYtyDUNL91qfiCJtVUX746QpNv5i5ve5",
+		"KT1PWx2mnDueood7fEmfbBDKx1D9BAnnXitn",
+	}
+	for _, address := range valid {
+		if !IsValidAddress(address) {
+			t.Errorf("expected %s to be valid", address)
+		}
+	}
+}
+
+func TestIsValidAddressWrong(t *testing.T) {
+	invalid := []string{
+		"",
+		"tz1",
+		"tz4Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecd",
+		"tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecc",
+		"tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZec0",
+		"tz1Wit2PqodvPeuRRhdQXmkrtU8e8bRYZecdd",
+	}
+	for _, address := range invalid {
+		if IsValidAddress(address) {
+			t.Errorf("expected %s to be invalid", address)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ibraheemacara/tezos-delegation-service/broker"
+	"github.com/ibraheemacara/tezos-delegation-service/config"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/httpclient"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+	"github.com/penglongli/gin-metrics/ginmetrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// headers of the deliveries, the signature authenticates the timestamp and the body
+const (
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+// queueSize is the number of deliveries waiting for a worker, the next ones are stored as dead letters right away
+const queueSize = 1000
+
+// deadLetterTimeout bounds the write of a dead letter, it is also done during the shutdown
+const deadLetterTimeout = 5 * time.Second
+
+const deliveriesMetric = "webhook_deliveries_total"
+
+var registerDeliveriesMetric sync.Once
+
+type delivery struct {
+	webhook db.Webhook
+	payload []byte
+}
+
+// Dispatcher posts the new delegations to the webhooks they match. Deliveries are done by a pool of workers
+// so that a slow webhook never holds back the ingestion.
+type Dispatcher struct {
+	db      db.DBInterface
+	client  *http.Client
+	retry   httpclient.RetryPolicy
+	workers int
+	queue   chan delivery
+	sleep   func(ctx context.Context, d time.Duration) error
+	now     func() time.Time
+}
+
+func NewDispatcher(cfg config.Config, db db.DBInterface) *Dispatcher {
+	// registered before any worker runs, the lazy creation of the monitor is not safe for concurrent use
+	registerDeliveriesMetric.Do(addDeliveriesMetric)
+	return &Dispatcher{
+		db: db,
+		client: &http.Client{
+			Timeout: cfg.Webhooks.Timeout,
+			// a redirected POST would be replayed as a GET, the redirection is a failed delivery instead
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		retry: httpclient.RetryPolicy{
+			MaxAttempts:    cfg.Webhooks.MaxAttempts,
+			InitialBackoff: cfg.Webhooks.InitialBackoff,
+			MaxBackoff:     cfg.Webhooks.MaxBackoff,
+		},
+		workers: max(cfg.Webhooks.Workers, 1),
+		queue:   make(chan delivery, queueSize),
+		sleep:   httpclient.Sleep,
+		now:     time.Now,
+	}
+}
+
+// Dispatch queues a delivery to every active webhook matched by some of the delegations, it does not wait for the deliveries
+func (d *Dispatcher) Dispatch(ctx context.Context, delegations []types.Delegation) {
+	webhooks, err := d.db.GetWebhooks(ctx)
+	if err != nil {
+		log.Errorf("Failed to get webhooks from database: %v", err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Active {
+			continue
+		}
+		filter := broker.Filter{Delegator: webhook.Delegator, Baker: webhook.Baker, MinAmount: webhook.MinAmount}
+		var matching []types.Delegation
+		for _, delegation := range delegations {
+			if filter.Match(delegation) {
+				matching = append(matching, delegation)
+			}
+		}
+		if len(matching) == 0 {
+			continue
+		}
+
+		payload, err := json.Marshal(types.WebhookPayload{WebhookID: webhook.ID, Delegations: matching})
+		if err != nil {
+			log.Errorf("Failed to marshal payload of webhook %d: %v", webhook.ID, err)
+			continue
+		}
+		select {
+		case d.queue <- delivery{webhook: webhook, payload: payload}:
+		default:
+			d.deadLetter(ctx, delivery{webhook: webhook, payload: payload}, 0, errors.New("delivery queue full"))
+		}
+	}
+}
+
+// Run delivers the queued payloads until ctx is cancelled, the deliveries still queued are then stored as dead letters
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range d.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case delivery := <-d.queue:
+					d.deliver(ctx, delivery)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for {
+		select {
+		case delivery := <-d.queue:
+			d.deadLetter(ctx, delivery, 0, ctx.Err())
+		default:
+			return
+		}
+	}
+}
+
+// deliver posts the payload until the webhook accepts it, the payload is stored as a dead letter once the attempts are exhausted
+func (d *Dispatcher) deliver(ctx context.Context, delivery delivery) {
+	attempts := max(d.retry.MaxAttempts, 1)
+	var err error
+	attempt := 0
+	for attempt < attempts {
+		attempt++
+		if err = d.send(ctx, delivery); err == nil {
+			countDelivery("delivered")
+			return
+		}
+		if attempt == attempts || ctx.Err() != nil {
+			break
+		}
+		wait := d.retry.Backoff(attempt, err)
+		log.Warnf("Delivery to webhook %d failed: %v, attempt %d/%d, retrying in %v", delivery.webhook.ID, err, attempt, attempts, wait)
+		if d.sleep(ctx, wait) != nil {
+			break
+		}
+	}
+	log.Errorf("Delivery to webhook %d failed after %d attempts: %v", delivery.webhook.ID, attempt, err)
+	d.deadLetter(ctx, delivery, attempt, err)
+}
+
+// send posts the payload once, any answer but a 2xx is a failure
+func (d *Dispatcher) send(ctx context.Context, delivery delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.webhook.Url, bytes.NewReader(delivery.payload))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(delivery.webhook.Secret, timestamp, delivery.payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// the body is drained so that the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &httpclient.StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+func (d *Dispatcher) deadLetter(ctx context.Context, delivery delivery, attempts int, err error) {
+	countDelivery("failed")
+	var message string
+	if err != nil {
+		message = err.Error()
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deadLetterTimeout)
+	defer cancel()
+	deadLetter := db.WebhookDeadLetter{
+		WebhookID: delivery.webhook.ID,
+		Payload:   string(delivery.payload),
+		Attempts:  attempts,
+		Error:     message,
+	}
+	if err := d.db.InsertWebhookDeadLetter(ctx, deadLetter); err != nil {
+		log.Errorf("Failed to store the dead letter of webhook %d: %v", delivery.webhook.ID, err)
+	}
+}
+
+// Sign returns the signature of a payload sent at timestamp: "sha256=" followed by the hex encoded
+// HMAC-SHA256 of "<timestamp>.<payload>" keyed with the secret of the webhook
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func addDeliveriesMetric() {
+	err := ginmetrics.GetMonitor().AddMetric(&ginmetrics.Metric{
+		Type:        ginmetrics.Counter,
+		Name:        deliveriesMetric,
+		Description: "Number of webhook deliveries by outcome",
+		Labels:      []string{"status"},
+	})
+	if err != nil {
+		log.Errorf("failed to add metric %v: %v", deliveriesMetric, err)
+	}
+}
+
+// countDelivery counts the deliveries by outcome, delivered or failed
+func countDelivery(status string) {
+	if err := ginmetrics.GetMonitor().GetMetric(deliveriesMetric).Inc([]string{status}); err != nil {
+		log.Errorf("error while incrementing metric %v: %v", deliveriesMetric, err)
+	}
+}
+
+// NewSecret returns a random secret for a webhook registered without one
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,209 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ibraheemacara/tezos-delegation-service/config"
+	"github.com/ibraheemacara/tezos-delegation-service/db"
+	"github.com/ibraheemacara/tezos-delegation-service/types"
+)
+
+type MockDB struct {
+	db.DBInterface
+	mu          sync.Mutex
+	webhooks    []db.Webhook
+	deadLetters []db.WebhookDeadLetter
+}
+
+func (m *MockDB) GetWebhooks(ctx context.Context) ([]db.Webhook, error) {
+	return m.webhooks, nil
+}
+
+func (m *MockDB) InsertWebhookDeadLetter(ctx context.Context, deadLetter db.WebhookDeadLetter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deadLetters = append(m.deadLetters, deadLetter)
+	return nil
+}
+
+func (m *MockDB) getDeadLetters() []db.WebhookDeadLetter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.deadLetters
+}
+
+// receiver records the deliveries it accepts, it answers failures with status until then
+type receiver struct {
+	mu         sync.Mutex
+	failures   int
+	status     int
+	attempts   int
+	deliveries []*http.Request
+	payloads   []types.WebhookPayload
+	bodies     [][]byte
+	received   chan struct{}
+}
+
+func newReceiver(failures, status int) (*receiver, *httptest.Server) {
+	r := &receiver{failures: failures, status: status, received: make(chan struct{}, 10)}
+	return r, httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.attempts++
+		if r.attempts <= r.failures {
+			w.WriteHeader(r.status)
+			return
+		}
+		var payload types.WebhookPayload
+		_ = json.Unmarshal(body, &payload)
+		r.deliveries = append(r.deliveries, req)
+		r.payloads = append(r.payloads, payload)
+		r.bodies = append(r.bodies, body)
+		r.received <- struct{}{}
+	}))
+}
+
+func newTestDispatcher(mockDB *MockDB, maxAttempts int) *Dispatcher {
+	cfg := config.Config{}
+	cfg.Webhooks.Workers = 2
+	cfg.Webhooks.Timeout = time.Second
+	cfg.Webhooks.MaxAttempts = maxAttempts
+	dispatcher := NewDispatcher(cfg, mockDB)
+	dispatcher.sleep = func(ctx context.Context, d time.Duration) error { return ctx.Err() }
+	return dispatcher
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	r, server := newReceiver(2, http.StatusServiceUnavailable)
+	defer server.Close()
+	mockDB := &MockDB{webhooks: []db.Webhook{
+		{ID: 1, Url: server.URL, Secret: "secret", Baker: "tz1baker", Active: true},
+		{ID: 2, Url: server.URL, Secret: "secret", MinAmount: 1000, Active: true},
+		{ID: 3, Url: server.URL, Secret: "secret", Active: false},
+	}}
+	dispatcher := newTestDispatcher(mockDB, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.Run(ctx)
+
+	dispatcher.Dispatch(ctx, []types.Delegation{
+		{Delegator: "tz1a", NewDelegate: "tz1baker", Amount: 10, Block: 1},
+		{Delegator: "tz1b", NewDelegate: "tz1other", Amount: 10, Block: 2},
+	})
+
+	select {
+	case <-r.received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery not received")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// the delivery succeeds at the third attempt
+	if r.attempts != 3 || len(r.payloads) != 1 {
+		t.Fatalf("expected 1 delivery after 3 attempts, got %d deliveries after %d attempts", len(r.payloads), r.attempts)
+	}
+	payload := r.payloads[0]
+	if payload.WebhookID != 1 || len(payload.Delegations) != 1 || payload.Delegations[0].Block != 1 {
+		t.Errorf("expected the delegation of block 1 for webhook 1, got %+v", payload)
+	}
+
+	req := r.deliveries[0]
+	timestamp := req.Header.Get(TimestampHeader)
+	if signature := req.Header.Get(SignatureHeader); signature != Sign("secret", timestamp, r.bodies[0]) {
+		t.Errorf("invalid signature %q", signature)
+	}
+	if len(mockDB.getDeadLetters()) != 0 {
+		t.Errorf("expected no dead letter, got %v", mockDB.getDeadLetters())
+	}
+}
+
+func TestDispatchDeadLetter(t *testing.T) {
+	r, server := newReceiver(10, http.StatusInternalServerError)
+	defer server.Close()
+	mockDB := &MockDB{webhooks: []db.Webhook{{ID: 1, Url: server.URL, Secret: "secret", Active: true}}}
+	dispatcher := newTestDispatcher(mockDB, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.Run(ctx)
+
+	dispatcher.Dispatch(ctx, []types.Delegation{{Delegator: "tz1a", Block: 1}})
+	waitFor(t, func() bool { return len(mockDB.getDeadLetters()) == 1 })
+
+	deadLetter := mockDB.getDeadLetters()[0]
+	if deadLetter.WebhookID != 1 || deadLetter.Attempts != 3 || deadLetter.Error != "non 200 status code: 500" {
+		t.Errorf("unexpected dead letter %+v", deadLetter)
+	}
+	var payload types.WebhookPayload
+	if err := json.Unmarshal([]byte(deadLetter.Payload), &payload); err != nil || len(payload.Delegations) != 1 {
+		t.Errorf("expected the payload to be kept, got %q", deadLetter.Payload)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", r.attempts)
+	}
+}
+
+func TestDispatchRedirectFails(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer server.Close()
+
+	dispatcher := newTestDispatcher(&MockDB{}, 1)
+	err := dispatcher.send(context.Background(), delivery{webhook: db.Webhook{Url: server.URL}, payload: []byte("{}")})
+	if err == nil {
+		t.Errorf("expected a redirection to fail the delivery")
+	}
+}
+
+func TestRunStoresQueuedDeliveries(t *testing.T) {
+	mockDB := &MockDB{webhooks: []db.Webhook{{ID: 1, Url: "http://127.0.0.1:0", Active: true}}}
+	dispatcher := newTestDispatcher(mockDB, 1)
+
+	// queued while no worker runs, then shut down right away
+	dispatcher.Dispatch(context.Background(), []types.Delegation{{Delegator: "tz1a"}, {Delegator: "tz1b"}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dispatcher.Run(ctx)
+
+	if len(mockDB.getDeadLetters()) != 1 {
+		t.Errorf("expected the queued delivery to be stored as a dead letter, got %v", mockDB.getDeadLetters())
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	expected := "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	if signature := Sign("secret", "1700000000", []byte("{}")); signature != expected {
+		t.Errorf("expected signature %q, got %q", expected, signature)
+	}
+	if Sign("secret", "1700000000", []byte("{}")) == Sign("other", "1700000000", []byte("{}")) {
+		t.Errorf("expected the signature to depend on the secret")
+	}
+	if Sign("secret", "1700000000", []byte("{}")) == Sign("secret", "1700000001", []byte("{}")) {
+		t.Errorf("expected the signature to depend on the timestamp")
+	}
+}